@@ -8,6 +8,7 @@ import (
 	"model"
 	"modules/logs/logger"
 	"modules/trigger"
+	"modules/websocket"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
@@ -83,6 +84,8 @@ func exitEvent(exitSignal ...string) {
 	}
 	// 停止looperEvent
 	application.neuron.Brain.ClearInterval(application.looperStopC)
+	// 持久化容器刷盘并关闭
+	application.neuron.Brain.PersistClose()
 	// redis销毁
 	if application.neuron.Redis != nil {
 		application.neuron.Redis.Pool.Close()
@@ -219,15 +222,44 @@ func serverProcess() *model.ServerS {
 		})
 
 		/* Construct Application Trigger */
-		trigger.On("EVAL", func(service string, function string, args []interface{}) {
+		trigger.On("EVAL", func(service string, function string, args []interface{}, caller string) {
 			argArr := make([]interface{}, 0, len(args)-2)
 			for _, v := range args[2:] {
 				argArr = append(argArr, v)
 			}
+			// 执行完成后释放Receiver预占的额度
+			if caller == model.RPCCommander {
+				defer application.neuron.Express.CreditRelease(fmt.Sprint(args[1]))
+			}
 			if application.neuron.Brain.Const.RunEnv < 2 {
 				application.neuron.Brain.LogGenerater(model.LogWarn, tag, "Eval -> "+service, fmt.Sprintf("%s(%s)", function, argArr))
 			}
+			// 类型化远程调用
+			if application.neuron.Express.RPCInvoke(service, function, caller, args) {
+				application.neuron.Express.TraceExecuted(caller, args, service+"."+function)
+				return
+			}
+			// 远程调用白名单
+			if code, data := application.neuron.Express.RPCCheck(server.Services[service], function, caller, args); code != 100 {
+				application.neuron.Brain.MessageHandler(tag, fmt.Sprintf("Eval -> %s.%s[%s]", service, function, caller), code, data)
+				if conn, found := args[0].(*websocket.Conn); found {
+					application.neuron.Express.RPCError(conn, fmt.Sprint(args[1]), service, function, code, data)
+				}
+				return
+			}
 			application.neuron.Brain.Eval(server.Services[service], function, args...)
+			application.neuron.Express.TraceExecuted(caller, args, service+"."+function)
+		})
+
+		/* Construct Node Trigger */
+		trigger.On("NODE", func(event string, node model.NodeS) {
+			for _, v := range server.Services {
+				if service, found := v.(model.NodeEventI); found {
+					go application.neuron.Brain.SafeFunction(func() {
+						service.NodeEvent(event, node)
+					})
+				}
+			}
 		})
 
 		/* Construct Terminal Trigger */
@@ -249,6 +281,11 @@ func serverProcess() *model.ServerS {
 			})
 		}
 
+		/* Register Services */
+		for k, v := range server.Services {
+			application.neuron.Services.Set(k, v)
+		}
+
 		application.neuron.Brain.LogGenerater(model.LogInfo, tag, server.Tag, "Prepared..")
 
 		go protocolHTTP(server, mux)
@@ -264,7 +301,7 @@ func protocolHTTP(server *model.ServerS, mux *http.ServeMux) {
 	listenPort := strconv.Itoa(application.neuron.Brain.Const.HTTPServer.Port)
 	listenAddr := application.neuron.Brain.Const.HTTPServer.Host + ":" + listenPort
 	application.neuron.Brain.LogGenerater(model.LogInfo, tag, server.Tag, "Listening port -> "+listenPort)
-	err := http.ListenAndServe(listenAddr, mux)
+	err := http.ListenAndServe(listenAddr, application.neuron.Express.Handler(mux))
 	if err != nil {
 		application.neuron.Brain.MessageHandler(tag, "Protocal -> HTTP", 204, err)
 		protocolTLS(server, mux)
@@ -281,7 +318,7 @@ func protocolTLS(server *model.ServerS, mux *http.ServeMux) {
 	// Get Crt & Key
 	crtPath := application.neuron.Brain.PathAbs(application.neuron.Brain.Const.HTTPS.TLSCertPath + ".crt")
 	keyPath := application.neuron.Brain.PathAbs(application.neuron.Brain.Const.HTTPS.TLSCertPath + ".key")
-	err := http.ListenAndServeTLS(listenAddr, crtPath, keyPath, mux)
+	err := http.ListenAndServeTLS(listenAddr, crtPath, keyPath, application.neuron.Express.Handler(mux))
 	if err != nil {
 		application.neuron.Brain.MessageHandler(tag, "Protocal -> TLS", 204, err)
 	}
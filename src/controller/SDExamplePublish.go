@@ -54,10 +54,12 @@ func (mExamplePublish *ExamplePublishS) exampleInterface() {
 	brain := mExamplePublish.neuron.Brain
 	mExamplePublish.mux.HandleFunc(mExamplePublish.Const.root+"/Example", func(res http.ResponseWriter, req *http.Request) {
 		mExamplePublish.neuron.Express.ConstructInterface(res, req, mExamplePublish.isStarted, func() {
-			//* Get/Post FormValue */
-			id := req.FormValue("id")
-			if brain.CheckIsNull(id) {
-				mExamplePublish.neuron.Express.CodeResponse(res, 207, "Lack of Parameter -> id", "exampleInterface")
+			//* Bind Query/Form/Body */
+			var param struct {
+				Id string `validate:"required"`
+			}
+			if code, data := mExamplePublish.neuron.Express.Bind(req, &param); code != 100 {
+				mExamplePublish.neuron.Express.CodeResponse(res, code, data, "exampleInterface")
 				return
 			}
 			//* SQL Query */
@@ -71,7 +73,7 @@ func (mExamplePublish *ExamplePublishS) exampleInterface() {
 			//	Id := dataDB[0].(string)
 			//	mExamplePublish.Log("Id", Id)
 			//})
-			mExamplePublish.requestTaskPush(id)
+			mExamplePublish.requestTaskPush(param.Id)
 			//* Response */
 			mExamplePublish.neuron.Express.CodeResponse(res, 100, "Success")
 		}, func(err interface{}) {
@@ -123,36 +125,27 @@ func (mExamplePublish *ExamplePublishS) behaviorLooper() {
 	if !brain.CheckIsNull(mExamplePublish.StopChannel.behaviorLooperSC) {
 		return
 	}
-	// 获取CommanderHub
-	hub := brain.Container.CommanderHub
-	// 设立索引缓存
-	selectIndex := 0
 	// 开始轮循
 	mExamplePublish.StopChannel.behaviorLooperSC = make(chan bool)
 	go brain.SetInterval(func() (int, interface{}) {
-		// 选择性广播
-		selectIndex = brain.SelectIterator(hub.Len(), selectIndex)
-		// 获取所有客户端Key
-		clientKeys := hub.Key2Slice(true)
-		for k, v := range clientKeys {
-			if selectIndex != k {
-				continue
-			}
-			neuronId := hub.Get(v).(model.SocketClient).Tag
+		// 选择运行twin服务、未繁忙且负载最低的节点
+		if nodes := mExamplePublish.neuron.Express.RouteNodes(mExamplePublish.Const.twin+"|least", mExamplePublish.Const.twin); len(nodes) > 0 {
+			neuronId := nodes[0].NeuronId
 			// 抛出UUID
 			uuid, found := mExamplePublish.Container.BehaviorForest.UUIDQ.Shift().(string)
 			if !found {
-				continue
+				return 100, nil
 			}
 			// 抛出UUID对应BTree
 			tree, found := mExamplePublish.Container.BehaviorForest.Trees.Pop(uuid).(*model.BehaviorTreeS)
 			if !found {
-				continue
+				return 100, nil
 			}
 			// BTree转Json
 			code, data := mExamplePublish.neuron.BehaviorTree.Tree2Json(tree)
 			if code != 100 {
-				continue
+				brain.MessageHandler(mExamplePublish.Const.tag, "behaviorLooper[Tree2Json]", code, data)
+				return 100, nil
 			}
 			mExamplePublish.neuron.Express.CommanderEval(neuronId, mExamplePublish.Const.twin, "BehaviorTreePush", data.([]byte))
 		}
@@ -182,6 +175,8 @@ func (mExamplePublish *ExamplePublishS) service() {
 //* 析构服务 */
 func (mExamplePublish *ExamplePublishS) serviceKiller() {
 	mExamplePublish.behaviorLooperKiller()
+	// 行为森林刷盘
+	mExamplePublish.neuron.Brain.PersistSync()
 }
 
 //* ================================ PUBLIC ================================ */
@@ -207,6 +202,28 @@ func (mExamplePublish *ExamplePublishS) IsStarted() bool {
 	return mExamplePublish.isStarted
 }
 
+//* 返回远程调用白名单 */
+func (mExamplePublish *ExamplePublishS) RPC() map[string]model.RPCS {
+	return map[string]model.RPCS{
+		"BehaviorTreeAnalyze":  {Args: []string{"string"}},
+		"BehaviorTreeError":    {Args: []string{"string"}},
+		"RemoteRequestAnalyze": {Args: []string{"string"}},
+	}
+}
+
+//* 返回队列深度 */
+func (mExamplePublish *ExamplePublishS) QueueDepth() map[string]int {
+	depth := make(map[string]int)
+	forest := mExamplePublish.Container.BehaviorForest
+	if !mExamplePublish.neuron.Brain.CheckIsNull(forest.UUIDQ) {
+		depth["UUIDQ"] = forest.UUIDQ.Len()
+	}
+	if !mExamplePublish.neuron.Brain.CheckIsNull(forest.ErrorQ) {
+		depth["ErrorQ"] = forest.ErrorQ.Len()
+	}
+	return depth
+}
+
 //* 启动服务 */
 func (mExamplePublish *ExamplePublishS) StartService() {
 	if mExamplePublish.isStarted {
@@ -13,6 +13,7 @@ import (
 	"model"
 	"modules/websocket"
 	"net/http"
+	"sync/atomic"
 )
 
 //* ================================ DEFINE ================================ */
@@ -25,6 +26,8 @@ type ExampleSubscribeS struct {
 	}
 	Container struct {
 		BehaviorTreeQ *model.QueueS
+		// 任务队列已满[额度恢复时上报]
+		saturated int32
 	}
 	Connection  struct{}
 	StopChannel struct {
@@ -42,6 +45,10 @@ type ExampleSubscribeS struct {
 func (mExampleSubscribe *ExampleSubscribeS) main() {
 	// Const Initialize
 	mExampleSubscribe.Const.twin = "/ExamplePublish"
+	// RPC Register
+	if code, data := mExampleSubscribe.neuron.Express.RPCRegister(mExampleSubscribe.Const.root, "RequestSync", mExampleSubscribe.requestSync, model.RPCCommander); code != 100 {
+		mExampleSubscribe.neuron.Brain.MessageHandler(mExampleSubscribe.Const.tag, "main[RPCRegister]", code, data)
+	}
 }
 
 //* ================================ INTERFACE ================================ */
@@ -51,10 +58,12 @@ func (mExampleSubscribe *ExampleSubscribeS) exampleInterface() {
 	brain := mExampleSubscribe.neuron.Brain
 	mExampleSubscribe.mux.HandleFunc(mExampleSubscribe.Const.root+"/Example", func(res http.ResponseWriter, req *http.Request) {
 		mExampleSubscribe.neuron.Express.ConstructInterface(res, req, mExampleSubscribe.isStarted, func() {
-			//* Get/Post FormValue */
-			id := req.FormValue("id")
-			if brain.CheckIsNull(id) {
-				mExampleSubscribe.neuron.Express.CodeResponse(res, 207, "Lack of Parameter -> id", "exampleInterface")
+			//* Bind Query/Form/Body */
+			var param struct {
+				Id string `validate:"required"`
+			}
+			if code, data := mExampleSubscribe.neuron.Express.Bind(req, &param); code != 100 {
+				mExampleSubscribe.neuron.Express.CodeResponse(res, code, data, "exampleInterface")
 				return
 			}
 			//* SQL Query */
@@ -79,6 +88,19 @@ func (mExampleSubscribe *ExampleSubscribeS) exampleInterface() {
 
 //* ================================ PROCESS ================================ */
 
+//* Commander -> 同步执行远程请求[CommanderRPC] */
+func (mExampleSubscribe *ExampleSubscribeS) requestSync(ctx model.RPCContextS, param *model.RequestParamS) (int, interface{}) {
+	if !mExampleSubscribe.isStarted {
+		return 204, "Interface Banned"
+	}
+	code, data := mExampleSubscribe.neuron.Brain.RequestSync(*param)
+	return 100, model.MessageS{
+		Code:    code,
+		Message: mExampleSubscribe.neuron.Brain.Const.ErrorCode[code],
+		Data:    data,
+	}
+}
+
 //* ================================ SQL PROCESS ================================ */
 
 //* ================================ TOOL ================================ */
@@ -94,6 +116,12 @@ func (mExampleSubscribe *ExampleSubscribeS) behaviorProcesser(receiverConn *webs
 	}
 	mExampleSubscribe.StopChannel.behaviorLooperSC = make(chan bool)
 	go brain.SetInterval(func() (int, interface{}) {
+		// 额度恢复则通知Commander继续派发
+		if mExampleSubscribe.Credit() != 0 && atomic.CompareAndSwapInt32(&mExampleSubscribe.Container.saturated, 1, 0) {
+			if err := mExampleSubscribe.neuron.Express.CreditReport(receiverConn); err != nil {
+				brain.MessageHandler(mExampleSubscribe.Const.tag, "behaviorProcesser[CreditReport]", 214, err)
+			}
+		}
 		if mExampleSubscribe.Container.BehaviorTreeQ.IsEmpty() {
 			return 103, "BehaviorTreeQ.IsEmpty"
 		}
@@ -162,6 +190,26 @@ func (mExampleSubscribe *ExampleSubscribeS) IsStarted() bool {
 	return mExampleSubscribe.isStarted
 }
 
+//* 返回远程调用白名单 */
+func (mExampleSubscribe *ExampleSubscribeS) RPC() map[string]model.RPCS {
+	return map[string]model.RPCS{
+		"BehaviorTreePush": {Args: []string{"string"}, Allow: []string{model.RPCCommander}},
+		"RemoteRequest":    {Args: []string{"string"}, Allow: []string{model.RPCCommander}},
+	}
+}
+
+//* 返回任务额度[QueueLimit<=0则不限制] */
+func (mExampleSubscribe *ExampleSubscribeS) Credit() int {
+	limit := mExampleSubscribe.neuron.Brain.Const.CommanderParam.QueueLimit
+	if limit <= 0 || mExampleSubscribe.Container.BehaviorTreeQ == nil {
+		return -1
+	}
+	if credit := limit - mExampleSubscribe.Container.BehaviorTreeQ.Len(); credit > 0 {
+		return credit
+	}
+	return 0
+}
+
 //* 启动服务 */
 func (mExampleSubscribe *ExampleSubscribeS) StartService() {
 	if mExampleSubscribe.isStarted {
@@ -215,8 +263,14 @@ func (mExampleSubscribe *ExampleSubscribeS) BehaviorTreePush(receiverConn *webso
 			brain.MessageHandler(mExampleSubscribe.Const.tag, "behaviorTreePush[Found]", 220, msgbuf.String())
 			return
 		}
-		// 消息队列
+		// 消息队列[额度已由Receiver于ACK前预占,队列已满时以BUSY拒收]
 		mExampleSubscribe.Container.BehaviorTreeQ.Push(tree)
+		// 额度用尽则立即通知Commander停止派发
+		if mExampleSubscribe.Credit() == 0 && atomic.CompareAndSwapInt32(&mExampleSubscribe.Container.saturated, 0, 1) {
+			if err := mExampleSubscribe.neuron.Express.CreditReport(receiverConn); err != nil {
+				brain.MessageHandler(mExampleSubscribe.Const.tag, "BehaviorTreePush[CreditReport]", 214, err)
+			}
+		}
 		// 启动任务机
 		mExampleSubscribe.behaviorProcesser(receiverConn, messageId)
 	}, func(err interface{}) {
@@ -13,6 +13,7 @@ import (
 	"compress/gzip"
 	"compress/zlib"
 	"crypto"
+	"crypto/aes"
 	"crypto/cipher"
 	"crypto/hmac"
 	"crypto/md5"
@@ -22,6 +23,7 @@ import (
 	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
 	"encoding/pem"
@@ -59,9 +61,32 @@ type BrainS struct {
 	Const     model.Const
 	Container struct {
 		CommanderHub   model.SyncMapHub /* map[IP]SocketClient */
-		CommanderQueue *model.QueueS
-		CommanderReply *model.QueueS
-	}
+		CommanderNodes model.SyncMapHub /* map[NeuronId]*model.NodeS */
+		CommanderQueue model.QueueI
+		CommanderReply model.QueueI
+		CommanderCall  model.SyncMapHub /* map[GMessageID]*model.CallS */
+		// 高优先级指令[High及以上,先于CommanderQueue派发]
+		CommanderPriority model.QueueI
+		// 投递中的指令
+		CommanderInflight model.MapI /* map[GMessageID@NeuronId]model.DeliveryS */
+		// 离线节点暂存
+		CommanderPending model.SyncMapHub /* map[NeuronId]model.QueueI */
+		// 死信队列
+		CommanderDeadLetter model.QueueI /* model.DeliveryS */
+		// 持久化容器
+		PersistHub model.SyncMapHub /* map[Name]model.PersistI */
+		// 集群共享存储
+		ClusterStore model.StoreI
+		// 已接收的加密nonce[防重放]
+		NonceHub model.SyncMapHub /* map[KeyId@Nonce]bool */
+		NonceQ   *model.QueueS    /* nonceS */
+	}
+}
+
+//* 防重放nonce记录 */
+type nonceS struct {
+	key    string
+	expire time.Time
 }
 
 //* ================================ System Function ================================ */
@@ -101,6 +126,8 @@ func (brain *BrainS) SystemSalt() []byte {
 func (brain *BrainS) Ontology() *BrainS {
 	brain.tag = "Brain"
 	brain.Const = brain.Const.Ontology()
+	brain.Container.NonceHub.Init("NonceHub")
+	brain.Container.NonceQ = new(model.QueueS).New()
 	return brain
 }
 
@@ -154,6 +181,208 @@ func (brain *BrainS) SystemDecrypt(encryptData []byte) []byte {
 	return result
 }
 
+//* 获取通信秘钥[Base64(32字节)直接使用,否则取口令SHA256] */
+func (brain *BrainS) securityKey(keyId string) ([]byte, bool) {
+	raw, found := brain.Const.Security.Keys[keyId]
+	if !found || raw == "" {
+		return nil, false
+	}
+	if key, err := base64.StdEncoding.DecodeString(raw); err == nil && len(key) == 32 {
+		return key, true
+	}
+	sum := sha256.Sum256([]byte(raw))
+	return sum[:], true
+}
+
+//* 是否接收旧格式[配置了有效ActiveKey时须显式开启Legacy] */
+func (brain *BrainS) securityLegacy() bool {
+	if brain.Const.Security.Legacy {
+		return true
+	}
+	_, found := brain.securityKey(brain.Const.Security.ActiveKey)
+	return !found
+}
+
+//* 通信加密[AES-256-GCM信封,未配置秘钥则使用SystemEncrypt] */
+/*
+* envelope:
+*   [magic 'N''E'][version][uvarint len][KeyId][timestamp int64 ms][nonce 12][ciphertext + tag]
+*   头部作为附加认证数据
+ */
+func (brain *BrainS) MessageEncrypt(decryptData []byte, keyIds ...string) []byte {
+	keyId := brain.Const.Security.ActiveKey
+	if len(keyIds) > 0 && keyIds[0] != "" {
+		keyId = keyIds[0]
+	}
+	key, found := brain.securityKey(keyId)
+	if !found {
+		return brain.SystemEncrypt(decryptData)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		brain.MessageHandler(brain.tag, "MessageEncrypt[NewCipher]", 209, err)
+		return nil
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		brain.MessageHandler(brain.tag, "MessageEncrypt[NewGCM]", 209, err)
+		return nil
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := randc.Read(nonce); err != nil {
+		brain.MessageHandler(brain.tag, "MessageEncrypt[Nonce]", 209, err)
+		return nil
+	}
+	var header bytes.Buffer
+	varint := make([]byte, binary.MaxVarintLen64)
+	header.Write(model.EnvelopeMagic)
+	header.WriteByte(model.EnvelopeVersion)
+	header.Write(varint[:binary.PutUvarint(varint, uint64(len(keyId)))])
+	header.WriteString(keyId)
+	binary.Write(&header, binary.BigEndian, time.Now().UnixNano()/int64(time.Millisecond))
+	header.Write(nonce)
+	return gcm.Seal(header.Bytes(), nonce, decryptData, header.Bytes())
+}
+
+//* 通信解密[返回明文及秘钥编号,失败返回nil] */
+func (brain *BrainS) MessageDecrypt(encryptData []byte) ([]byte, string) {
+	magicLen := len(model.EnvelopeMagic)
+	if len(encryptData) <= magicLen || !bytes.HasPrefix(encryptData, model.EnvelopeMagic) || encryptData[magicLen] != model.EnvelopeVersion {
+		// 旧格式
+		if !brain.securityLegacy() {
+			brain.MessageHandler(brain.tag, "MessageDecrypt", 208, "Legacy Format Refused")
+			return nil, ""
+		}
+		return brain.SystemDecrypt(encryptData), ""
+	}
+	reader := bytes.NewReader(encryptData[magicLen+1:])
+	keyLen, err := binary.ReadUvarint(reader)
+	if err != nil || keyLen > uint64(reader.Len()) {
+		return nil, ""
+	}
+	keyIdB := make([]byte, keyLen)
+	io.ReadFull(reader, keyIdB)
+	keyId := string(keyIdB)
+	key, found := brain.securityKey(keyId)
+	if !found {
+		brain.MessageHandler(brain.tag, "MessageDecrypt", 208, "Unknown KeyId -> "+keyId)
+		return nil, ""
+	}
+	var timestamp int64
+	if err := binary.Read(reader, binary.BigEndian, &timestamp); err != nil {
+		return nil, ""
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, ""
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, ""
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(reader, nonce); err != nil {
+		return nil, ""
+	}
+	headerLen := len(encryptData) - reader.Len()
+	decryptData, err := gcm.Open(nil, nonce, encryptData[headerLen:], encryptData[:headerLen])
+	if err != nil {
+		brain.MessageHandler(brain.tag, "MessageDecrypt[Open]", 208, err)
+		return nil, ""
+	}
+	// 时间戳及nonce防重放
+	window := time.Duration(brain.Const.Security.ReplayWindow) * time.Millisecond
+	sent := time.Unix(0, timestamp*int64(time.Millisecond))
+	if now := time.Now(); sent.Before(now.Add(-window)) || sent.After(now.Add(window)) {
+		brain.MessageHandler(brain.tag, "MessageDecrypt[Replay]", 208, fmt.Sprintf("Timestamp Expired -> %v", sent))
+		return nil, ""
+	}
+	if brain.isReplayed(keyId+"@"+brain.HEXEncoder(nonce), sent.Add(window)) {
+		brain.MessageHandler(brain.tag, "MessageDecrypt[Replay]", 208, "Nonce Reused -> "+keyId)
+		return nil, ""
+	}
+	return decryptData, keyId
+}
+
+//* 秘钥是否允许该节点使用[Scopes未限定则不限] */
+func (brain *BrainS) SecurityScoped(keyId string, neuronId string) bool {
+	neuronIds := brain.Const.Security.Scopes[keyId]
+	if keyId == "" || len(neuronIds) == 0 {
+		return true
+	}
+	for _, v := range neuronIds {
+		if v == neuronId {
+			return true
+		}
+	}
+	return false
+}
+
+//* 记录nonce,返回是否已出现过[过期记录按先进先出淘汰] */
+func (brain *BrainS) isReplayed(nonceKey string, expire time.Time) bool {
+	now := time.Now()
+	for {
+		nonce, found := brain.Container.NonceQ.Shift().(nonceS)
+		if !found {
+			break
+		}
+		if nonce.expire.After(now) {
+			brain.Container.NonceQ.UnShift(nonce)
+			break
+		}
+		brain.Container.NonceHub.Del(nonce.key)
+	}
+	if _, loaded := brain.Container.NonceHub.GetOrSet(nonceKey, true); loaded {
+		return true
+	}
+	brain.Container.NonceQ.Push(nonceS{nonceKey, expire})
+	return false
+}
+
+//* 生成握手凭据[hmac:<secret> -> HEX(HMAC) | rsa:<私钥PEM路径> -> Base64(签名)] */
+func (brain *BrainS) JoinProof(credential string, challenge string, neuronId string) (int, interface{}) {
+	plain := []byte(challenge + "@" + neuronId)
+	switch {
+	case strings.HasPrefix(credential, "hmac:"):
+		return 100, brain.HmacSha256Encode(plain, strings.TrimPrefix(credential, "hmac:"))
+	case strings.HasPrefix(credential, "rsa:"):
+		code, data := brain.FileReader(brain.PathAbs(strings.TrimPrefix(credential, "rsa:")))
+		if code != 100 {
+			return code, data
+		}
+		var proof string
+		brain.SafeFunction(func() {
+			proof = brain.Base64Encoder(brain.RSASign(brain.Bytes2PrivateKey(data.([]byte)), plain))
+		})
+		if proof == "" {
+			return 208, "JoinProof -> RSASign Failed"
+		}
+		return 100, proof
+	}
+	return 208, "JoinProof -> Credential Error"
+}
+
+//* 验证握手凭据[hmac:<secret> | rsa:<公钥PEM路径>] */
+func (brain *BrainS) JoinVerify(credential string, challenge string, neuronId string, proof string) bool {
+	plain := []byte(challenge + "@" + neuronId)
+	switch {
+	case strings.HasPrefix(credential, "hmac:"):
+		return hmac.Equal([]byte(brain.HmacSha256Encode(plain, strings.TrimPrefix(credential, "hmac:"))), []byte(proof))
+	case strings.HasPrefix(credential, "rsa:"):
+		code, data := brain.FileReader(brain.PathAbs(strings.TrimPrefix(credential, "rsa:")))
+		if code != 100 {
+			brain.MessageHandler(brain.tag, "JoinVerify[FileReader]", code, data)
+			return false
+		}
+		verified := false
+		brain.SafeFunction(func() {
+			verified = brain.RSAVerify(brain.Bytes2PublicKey(data.([]byte)), plain, brain.Base64Decoder(proof))
+		})
+		return verified
+	}
+	return false
+}
+
 //* 根据NeuronSplit分割字符串 */
 func (brain *BrainS) SystemSplit(str string) []string {
 	return strings.Split(str, brain.Const.SystemSplit)
@@ -668,6 +897,209 @@ func (brain *BrainS) analyzeMessage(msg string, split string) *model.GMessageS {
 	return GMessage
 }
 
+//* 生成二进制指令[参数按类型保留,不做字符转义] */
+/*
+* frame:
+*   [magic 'N''F'][version][uvarint bodyLen][body]
+* body:
+*   [uvarint len][ID] [uvarint len][Head] [uvarint len][Tag] [uvarint count] ([type][uvarint len][data])... ([uvarint len][Trace])
+* trace:
+*   可选,旧版本解析时忽略
+* type:
+*   FrameBytes -> []byte | FrameString -> string | FrameInt -> int64 | FrameJSON -> 其他类型
+ */
+func (brain *BrainS) GenerateFrame(head string, tag string, cmds []interface{}, id ...string) []byte {
+	ids := ""
+	if len(id) != 0 {
+		ids = id[0]
+	}
+	return brain.generateFrame(head, tag, cmds, ids, "")
+}
+
+//* 生成二进制指令[trace非空则附加追踪编号字段] */
+func (brain *BrainS) generateFrame(head string, tag string, cmds []interface{}, ids string, trace string) []byte {
+	var body bytes.Buffer
+	varint := make([]byte, binary.MaxVarintLen64)
+	writeField := func(b []byte) {
+		body.Write(varint[:binary.PutUvarint(varint, uint64(len(b)))])
+		body.Write(b)
+	}
+	writeField([]byte(ids))
+	writeField([]byte(head))
+	writeField([]byte(tag))
+	body.Write(varint[:binary.PutUvarint(varint, uint64(len(cmds)))])
+	for _, v := range cmds {
+		switch vv := v.(type) {
+		case []byte:
+			body.WriteByte(model.FrameBytes)
+			writeField(vv)
+		case string:
+			body.WriteByte(model.FrameString)
+			writeField([]byte(vv))
+		case int, int8, int16, int32, int64:
+			body.WriteByte(model.FrameInt)
+			number := make([]byte, binary.MaxVarintLen64)
+			writeField(number[:binary.PutVarint(number, reflect.ValueOf(vv).Int())])
+		default:
+			body.WriteByte(model.FrameJSON)
+			data, err := json.Marshal(vv)
+			if err != nil {
+				data = []byte(fmt.Sprintf("%q", fmt.Sprintf("%v", vv)))
+			}
+			writeField(data)
+		}
+	}
+	if trace != "" {
+		writeField([]byte(trace))
+	}
+	var frame bytes.Buffer
+	frame.Write(model.FrameMagic)
+	frame.WriteByte(model.FrameVersion)
+	frame.Write(varint[:binary.PutUvarint(varint, uint64(body.Len()))])
+	frame.Write(body.Bytes())
+	return frame.Bytes()
+}
+
+//* 判断是否为二进制指令 */
+func (brain *BrainS) IsFrame(data []byte) bool {
+	return len(data) > len(model.FrameMagic) && bytes.HasPrefix(data, model.FrameMagic) && data[len(model.FrameMagic)] == model.FrameVersion
+}
+
+//* 解析二进制指令[支持多帧拼接] */
+func (brain *BrainS) AnalyzeFrame(data []byte) ([]*model.GMessageS, error) {
+	var result []*model.GMessageS
+	reader := bytes.NewReader(data)
+	readField := func(r *bytes.Reader) ([]byte, error) {
+		length, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if length > uint64(r.Len()) {
+			return nil, fmt.Errorf("AnalyzeFrame -> Length Error")
+		}
+		field := make([]byte, length)
+		_, err = io.ReadFull(r, field)
+		return field, err
+	}
+	for reader.Len() > 0 {
+		header := make([]byte, len(model.FrameMagic)+1)
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(header[:len(model.FrameMagic)], model.FrameMagic) {
+			return nil, fmt.Errorf("AnalyzeFrame -> Magic Error")
+		}
+		if header[len(model.FrameMagic)] != model.FrameVersion {
+			return nil, fmt.Errorf("AnalyzeFrame -> Version Error [%d]", header[len(model.FrameMagic)])
+		}
+		body, err := readField(reader)
+		if err != nil {
+			return nil, err
+		}
+		bodyReader := bytes.NewReader(body)
+		GMessage := new(model.GMessageS)
+		fields := make([][]byte, 3)
+		for k := range fields {
+			if fields[k], err = readField(bodyReader); err != nil {
+				return nil, err
+			}
+		}
+		GMessage.ID = string(fields[0])
+		GMessage.Head = string(fields[1])
+		GMessage.Tag = string(fields[2])
+		count, err := binary.ReadUvarint(bodyReader)
+		if err != nil {
+			return nil, err
+		}
+		if count > uint64(bodyReader.Len()) {
+			return nil, fmt.Errorf("AnalyzeFrame -> Count Error")
+		}
+		if count > 0 {
+			GMessage.Cmds = make([]interface{}, 0, count)
+		}
+		for i := uint64(0); i < count; i++ {
+			typ, err := bodyReader.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			field, err := readField(bodyReader)
+			if err != nil {
+				return nil, err
+			}
+			switch typ {
+			case model.FrameBytes:
+				GMessage.Cmds = append(GMessage.Cmds, field)
+			case model.FrameString:
+				GMessage.Cmds = append(GMessage.Cmds, string(field))
+			case model.FrameInt:
+				n, _ := binary.Varint(field)
+				GMessage.Cmds = append(GMessage.Cmds, n)
+			case model.FrameJSON:
+				var v interface{}
+				if err := json.Unmarshal(field, &v); err != nil {
+					return nil, err
+				}
+				GMessage.Cmds = append(GMessage.Cmds, v)
+			default:
+				return nil, fmt.Errorf("AnalyzeFrame -> Type Error [%d]", typ)
+			}
+		}
+		// 可选的追踪编号字段
+		if bodyReader.Len() > 0 {
+			trace, err := readField(bodyReader)
+			if err != nil {
+				return nil, err
+			}
+			GMessage.Trace = string(trace)
+		}
+		result = append(result, GMessage)
+	}
+	return result, nil
+}
+
+//* 按编码格式生成指令[追踪编号仅由二进制格式及CodecTextTrace携带] */
+func (brain *BrainS) EncodeMessage(codec string, gMsg *model.GMessageS) []byte {
+	switch codec {
+	case model.CodecBinary:
+		return brain.generateFrame(gMsg.Head, gMsg.Tag, gMsg.Cmds, gMsg.ID, gMsg.Trace)
+	case model.CodecTextTrace:
+		if gMsg.Trace != "" {
+			return brain.GenerateMessage(gMsg.Head, gMsg.Tag, gMsg.Cmds, gMsg.ID+model.TraceSeparator+gMsg.Trace).Bytes()
+		}
+	}
+	return brain.GenerateMessage(gMsg.Head, gMsg.Tag, gMsg.Cmds, gMsg.ID).Bytes()
+}
+
+//* 从ID中拆分追踪编号[仅用于CodecTextTrace] */
+func (brain *BrainS) traceSplit(gMsg *model.GMessageS) *model.GMessageS {
+	if gMsg == nil {
+		return nil
+	}
+	if index := strings.LastIndex(gMsg.ID, model.TraceSeparator); index != -1 {
+		gMsg.ID, gMsg.Trace = gMsg.ID[:index], gMsg.ID[index+len(model.TraceSeparator):]
+	}
+	return gMsg
+}
+
+//* 自动识别编码格式解析指令[codec -> 连接协商的编码格式,CodecTextTrace时拆分追踪编号] */
+func (brain *BrainS) DecodeMessage(data []byte, codecs ...string) []*model.GMessageS {
+	if brain.IsFrame(data) {
+		result, err := brain.AnalyzeFrame(data)
+		if err != nil {
+			brain.MessageHandler(brain.tag, "DecodeMessage[AnalyzeFrame]", 209, err)
+			return nil
+		}
+		return result
+	}
+	result := brain.AnalyzeMessage(string(data))
+	if len(codecs) > 0 && codecs[0] == model.CodecTextTrace {
+		for _, v := range result {
+			brain.traceSplit(v)
+		}
+	}
+	return result
+}
+
 //* 构造绝对路径 */
 func (brain *BrainS) PathAbs(dirPath string) string {
 	dirPath = strings.Replace(dirPath, "../", "", -1)
@@ -907,6 +1339,51 @@ func (brain *BrainS) File2Struct(FileName string) (int, interface{}) {
 	return 100, buf.Bytes()
 }
 
+//* 持久化队列 -> /data/{name}/*.wal[重放后返回model.QueueI] */
+func (brain *BrainS) PersistQueue(name string, codec model.PersistCodecS, maxLen ...int) (int, interface{}) {
+	DirPath := brain.PathAbs(fmt.Sprintf("/data/%s", name))
+	queue, err := new(model.PersistQueueS).New(DirPath, brain.Const.Persistence.SegmentSize, brain.Const.Persistence.SyncInterval, codec, maxLen...)
+	if err != nil {
+		return 205, err
+	}
+	brain.Container.PersistHub.Set(name, queue)
+	return 100, queue
+}
+
+//* 持久化map容器 -> /data/{name}/*.wal[重放后返回model.MapI] */
+func (brain *BrainS) PersistMap(name string, codec model.PersistCodecS) (int, interface{}) {
+	DirPath := brain.PathAbs(fmt.Sprintf("/data/%s", name))
+	hub, err := new(model.PersistMapS).New(DirPath, brain.Const.Persistence.SegmentSize, brain.Const.Persistence.SyncInterval, codec, name)
+	if err != nil {
+		return 205, err
+	}
+	brain.Container.PersistHub.Set(name, hub)
+	return 100, hub
+}
+
+//* 刷盘全部持久化容器[服务停止时调用] */
+func (brain *BrainS) PersistSync() {
+	brain.Container.PersistHub.Iterator(func(n int, k string, v interface{}) bool {
+		if persist, found := v.(model.PersistI); found {
+			if err := persist.Sync(); err != nil {
+				brain.MessageHandler(brain.tag, "PersistSync["+k+"]", 205, err)
+			}
+		}
+		return true
+	})
+}
+
+//* 刷盘并关闭全部持久化容器[进程退出时调用] */
+func (brain *BrainS) PersistClose() {
+	for _, k := range brain.Container.PersistHub.Key2Slice() {
+		if persist, found := brain.Container.PersistHub.Pop(k).(model.PersistI); found {
+			if err := persist.Close(); err != nil {
+				brain.MessageHandler(brain.tag, "PersistClose["+k+"]", 205, err)
+			}
+		}
+	}
+}
+
 //* HTTP Request */
 /* 构造Post方法 -> [
 	host, path := express.Url2HostPath(u)
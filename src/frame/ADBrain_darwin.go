@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"model"
 	"os/exec"
+	"strconv"
+	"strings"
 	"syscall"
 )
 
@@ -55,3 +57,21 @@ func (brain *BrainS) SystemService(stopC chan bool, osCommand map[string]string,
 	})
 	return codeR, dataR
 }
+
+//* 获取1分钟平均负载 */
+func (brain *BrainS) SystemLoad() float64 {
+	// { 1.23 1.10 1.00 }
+	data, err := exec.Command("sysctl", "-n", "vm.loadavg").Output()
+	if err != nil {
+		return -1
+	}
+	fields := strings.Fields(strings.Trim(strings.TrimSpace(string(data)), "{}"))
+	if len(fields) == 0 {
+		return -1
+	}
+	load, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return -1
+	}
+	return load
+}
@@ -3,8 +3,11 @@ package frame
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"model"
 	"os/exec"
+	"strconv"
+	"strings"
 	"syscall"
 )
 
@@ -55,3 +58,20 @@ func (brain *BrainS) SystemService(stopC chan bool, osCommand map[string]string,
 	})
 	return codeR, dataR
 }
+
+//* 获取1分钟平均负载 */
+func (brain *BrainS) SystemLoad() float64 {
+	data, err := ioutil.ReadFile("/proc/loadavg")
+	if err != nil {
+		return -1
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return -1
+	}
+	load, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return -1
+	}
+	return load
+}
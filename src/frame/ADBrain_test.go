@@ -0,0 +1,222 @@
+package frame
+
+import (
+	"bytes"
+	"model"
+	"reflect"
+	"testing"
+)
+
+//* 构造测试用Brain */
+func testBrain() *BrainS {
+	return new(BrainS).Ontology()
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	brain := testBrain()
+	cases := []struct {
+		name string
+		msg  model.GMessageS
+		want []interface{}
+	}{
+		{"empty", model.GMessageS{Head: "!", Tag: "HEART"}, nil},
+		{"id", model.GMessageS{ID: "1", Head: "?", Tag: "EVAL"}, nil},
+		{"separator", model.GMessageS{ID: "a#b", Head: "?", Tag: "EVAL", Cmds: []interface{}{"x#y**"}}, []interface{}{"x#y**"}},
+		{"bytes", model.GMessageS{ID: "2", Head: "~", Tag: "DATA", Cmds: []interface{}{[]byte{0, 1, 255}}}, []interface{}{[]byte{0, 1, 255}}},
+		{"int", model.GMessageS{ID: "3", Head: "?", Tag: "EVAL", Cmds: []interface{}{-7, int64(1 << 40)}}, []interface{}{int64(-7), int64(1 << 40)}},
+		{"json", model.GMessageS{ID: "4", Head: "?", Tag: "EVAL", Cmds: []interface{}{map[string]int{"a": 1}, true}}, []interface{}{map[string]interface{}{"a": float64(1)}, true}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			data := brain.EncodeMessage(model.CodecBinary, &c.msg)
+			if !brain.IsFrame(data) {
+				t.Fatal("encoded data is not a frame")
+			}
+			result := brain.DecodeMessage(data)
+			if len(result) != 1 {
+				t.Fatalf("decoded %d messages, want 1", len(result))
+			}
+			got := result[0]
+			if got.ID != c.msg.ID || got.Head != c.msg.Head || got.Tag != c.msg.Tag {
+				t.Fatalf("decoded %v%v[%v], want %v%v[%v]", got.Head, got.Tag, got.ID, c.msg.Head, c.msg.Tag, c.msg.ID)
+			}
+			if !reflect.DeepEqual(got.Cmds, c.want) {
+				t.Fatalf("cmds = %#v, want %#v", got.Cmds, c.want)
+			}
+		})
+	}
+}
+
+func TestFrameConcat(t *testing.T) {
+	brain := testBrain()
+	var data bytes.Buffer
+	data.Write(brain.GenerateFrame("?", "EVAL", []interface{}{"a"}, "1"))
+	data.Write(brain.GenerateFrame("!", "ACK", nil, "1"))
+	result, err := brain.AnalyzeFrame(data.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 2 || result[0].Tag != "EVAL" || result[1].Tag != "ACK" {
+		t.Fatalf("concat decoded %v", result)
+	}
+}
+
+func TestFrameMalformed(t *testing.T) {
+	brain := testBrain()
+	frame := brain.GenerateFrame("?", "EVAL", []interface{}{"abc", 1}, "1")
+	cases := []struct {
+		name string
+		data []byte
+	}{
+		{"truncated", frame[:len(frame)-1]},
+		{"magic", append([]byte("XX"), frame[2:]...)},
+		{"version", append(append(append([]byte{}, model.FrameMagic...), model.FrameVersion+1), frame[len(model.FrameMagic)+1:]...)},
+		{"trailing", append(append([]byte{}, frame...), model.FrameMagic...)},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := brain.AnalyzeFrame(c.data); err == nil {
+				t.Fatal("malformed frame decoded without error")
+			}
+		})
+	}
+}
+
+func TestTextRoundTrip(t *testing.T) {
+	brain := testBrain()
+	msg := model.GMessageS{ID: "1", Head: "?", Tag: "EVAL", Cmds: []interface{}{"a", "b"}}
+	result := brain.DecodeMessage(brain.EncodeMessage(model.CodecText, &msg))
+	if len(result) != 1 || result[0].ID != "1" || !reflect.DeepEqual(result[0].Cmds, []interface{}{"a", "b"}) {
+		t.Fatalf("text decoded %v", result)
+	}
+}
+
+func TestTrace(t *testing.T) {
+	brain := testBrain()
+	cases := []struct {
+		name  string
+		codec string
+		msg   model.GMessageS
+		id    string
+		trace string
+	}{
+		{"binary", model.CodecBinary, model.GMessageS{ID: "1", Head: "?", Tag: "EVAL", Trace: "t1"}, "1", "t1"},
+		{"binaryPipe", model.CodecBinary, model.GMessageS{ID: "a|b", Head: "?", Tag: "EVAL"}, "a|b", ""},
+		{"text", model.CodecText, model.GMessageS{ID: "1", Head: "?", Tag: "EVAL", Trace: "t1"}, "1", ""},
+		{"textPipe", model.CodecText, model.GMessageS{ID: "a|b", Head: "?", Tag: "EVAL"}, "a|b", ""},
+		{"textTrace", model.CodecTextTrace, model.GMessageS{ID: "1", Head: "?", Tag: "EVAL", Trace: "t1"}, "1", "t1"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result := brain.DecodeMessage(brain.EncodeMessage(c.codec, &c.msg), c.codec)
+			if len(result) != 1 || result[0].ID != c.id || result[0].Trace != c.trace {
+				t.Fatalf("decoded %v, want id %v trace %v", result, c.id, c.trace)
+			}
+		})
+	}
+}
+
+//* 配置通信秘钥 */
+func securityBrain(legacy bool) *BrainS {
+	brain := testBrain()
+	brain.Const.Security.ActiveKey = "k1"
+	brain.Const.Security.Keys = map[string]string{"k1": "secret-1", "k2": "secret-2"}
+	brain.Const.Security.Legacy = legacy
+	brain.Const.Security.ReplayWindow = 60000
+	return brain
+}
+
+func TestEnvelope(t *testing.T) {
+	plain := []byte("123#?EVAL#a#b**")
+	cases := []struct {
+		name   string
+		legacy bool
+		data   func(brain *BrainS) []byte
+		want   []byte
+		keyId  string
+	}{
+		{"active", false, func(brain *BrainS) []byte {
+			return brain.MessageEncrypt(plain)
+		}, plain, "k1"},
+		{"keyId", false, func(brain *BrainS) []byte {
+			return brain.MessageEncrypt(plain, "k2")
+		}, plain, "k2"},
+		{"replay", false, func(brain *BrainS) []byte {
+			data := brain.MessageEncrypt(plain)
+			brain.MessageDecrypt(data)
+			return data
+		}, nil, ""},
+		{"tampered", false, func(brain *BrainS) []byte {
+			data := brain.MessageEncrypt(plain)
+			data[len(data)-1] ^= 1
+			return data
+		}, nil, ""},
+		{"header", false, func(brain *BrainS) []byte {
+			// 头部为附加认证数据,时间戳末字节被改动后仍在窗口内但无法解密
+			data := brain.MessageEncrypt(plain, "k1")
+			data[len(model.EnvelopeMagic)+1+1+len("k1")+7] ^= 1
+			return data
+		}, nil, ""},
+		{"unknownKey", false, func(brain *BrainS) []byte {
+			data := brain.MessageEncrypt(plain, "k2")
+			delete(brain.Const.Security.Keys, "k2")
+			return data
+		}, nil, ""},
+		{"expired", false, func(brain *BrainS) []byte {
+			data := brain.MessageEncrypt(plain)
+			brain.Const.Security.ReplayWindow = -1000
+			return data
+		}, nil, ""},
+		{"legacyRefused", false, func(brain *BrainS) []byte {
+			return brain.SystemEncrypt(plain)
+		}, nil, ""},
+		{"legacyAllowed", true, func(brain *BrainS) []byte {
+			return brain.SystemEncrypt(plain)
+		}, plain, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			brain := securityBrain(c.legacy)
+			got, keyId := brain.MessageDecrypt(c.data(brain))
+			if !bytes.Equal(got, c.want) || keyId != c.keyId {
+				t.Fatalf("decrypt = %q[%v], want %q[%v]", got, keyId, c.want, c.keyId)
+			}
+		})
+	}
+}
+
+func TestEnvelopeNonce(t *testing.T) {
+	brain := securityBrain(false)
+	first, second := brain.MessageEncrypt([]byte("a")), brain.MessageEncrypt([]byte("a"))
+	if bytes.Equal(first, second) {
+		t.Fatal("envelopes with fresh nonces should differ")
+	}
+	for _, v := range [][]byte{first, second} {
+		if got, _ := brain.MessageDecrypt(v); string(got) != "a" {
+			t.Fatalf("decrypt = %q, want a", got)
+		}
+	}
+	if got, _ := brain.MessageDecrypt(first); got != nil {
+		t.Fatal("reused nonce accepted")
+	}
+}
+
+func TestSecurityScoped(t *testing.T) {
+	brain := securityBrain(false)
+	brain.Const.Security.Scopes = map[string][]string{"k1": {"Neuron"}}
+	cases := []struct {
+		keyId    string
+		neuronId string
+		want     bool
+	}{
+		{"k1", "Neuron", true},
+		{"k1", "Other", false},
+		{"k2", "Other", true},
+		{"", "Other", true},
+	}
+	for _, c := range cases {
+		if got := brain.SecurityScoped(c.keyId, c.neuronId); got != c.want {
+			t.Fatalf("SecurityScoped(%v, %v) = %v, want %v", c.keyId, c.neuronId, got, c.want)
+		}
+	}
+}
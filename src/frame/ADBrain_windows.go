@@ -47,3 +47,8 @@ func (brain *BrainS) SystemService(stopC chan bool, osCommand map[string]string,
 	})
 	return codeR, dataR
 }
+
+//* 获取1分钟平均负载[Windows无此指标] */
+func (brain *BrainS) SystemLoad() float64 {
+	return -1
+}
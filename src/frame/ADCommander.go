@@ -8,12 +8,17 @@
 package frame
 
 import (
+	"bytes"
 	"fmt"
 	"model"
 	"modules/trigger"
 	"modules/websocket"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 //* ================================ DEFINE ================================ */
@@ -22,15 +27,53 @@ type CommanderS struct {
 		tag  string
 		root string
 	}
-	Container   struct{}
+	Container struct {
+		// 节点注册信息写锁[NodeS写时复制,读取无需加锁]
+		nodeMutex sync.Mutex
+		// 集群主节点
+		leaderMutex sync.RWMutex
+		leader      string
+		isLeader    bool
+		// 主节点租约到期时间[超出则停止派发]
+		leaseExpire time.Time
+		// 节点发送队列
+		sendMutex   sync.Mutex
+		sendQueues  map[string]*nodeSendS
+		sendBacklog int64
+		// 延迟指令[内存索引,指令保存于delayedHub]
+		delayed    *model.DelayQueueS
+		delayedHub model.MapI /* map[UUID]model.CommanderPiece */
+		// 已移入发送队列的指令[发送后移除,重启或接管时退回待派发队列]
+		staged model.MapI /* map[UUID]model.CommanderPiece */
+		// 节点连接索引[经CommanderHub校验]
+		nodeConns model.SyncMapHub /* map[NeuronId]*websocket.Conn */
+	}
 	Connection  struct{}
 	StopChannel struct {
 		CommanderLooperSC chan bool
+		callLooperSC      chan bool
+		deliveryLooperSC  chan bool
+		nodeLooperSC      chan bool
+		electionLooperSC  chan bool
 	}
 
 	isStarted bool
 	neuron    *NeuronS
 	mux       *http.ServeMux
+	router    *RouterS
+}
+
+//* 调度中的指令[key -> delayedHub/staged中的键] */
+type scheduleItemS struct {
+	key   string
+	piece model.CommanderPiece
+}
+
+//* 节点发送队列 */
+type nodeSendS struct {
+	queue   *model.PriorityQueueS
+	rate    *model.RateS
+	running bool
 }
 
 //* ================================ INNER INTERFACE ================================ */
@@ -43,12 +86,14 @@ func (mCommander *CommanderS) GMessageHandler(clientI interface{}, msgI interfac
 	ws := clientI.(model.SocketClient).Conn.(*websocket.Conn)
 	msg := msgI.([]byte)
 	// 解密
-	msgData := mCommander.neuron.Brain.SystemDecrypt(msg)
+	msgData, keyId := mCommander.neuron.Brain.MessageDecrypt(msg)
 	if mCommander.neuron.Brain.CheckIsNull(msgData) {
-		mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, mCommander.neuron.Brain.Container.CommanderHub.Tag+" -> SystemDecrypt", 203, "[Visitor -> "+ws.Request().RemoteAddr+"]")
+		mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, mCommander.neuron.Brain.Container.CommanderHub.Tag+" -> MessageDecrypt", 203, "[Visitor -> "+ws.Request().RemoteAddr+"]")
 	} else {
+		// 回复使用对端秘钥[秘钥轮换期间新旧节点并存]
+		mCommander.neuron.Express.WSKeySet(ws, keyId)
 		// 解码
-		GMessageArr := mCommander.neuron.Brain.AnalyzeMessage(string(msgData))
+		GMessageArr := mCommander.neuron.Brain.DecodeMessage(msgData, mCommander.neuron.Express.WSCodec(ws))
 		if mCommander.neuron.Brain.CheckIsNull(GMessageArr) {
 			mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, mCommander.neuron.Brain.Container.CommanderHub.Tag+" -> AnalyzeMessage", 203, "[Visitor -> "+ws.Request().RemoteAddr+"]")
 		} else {
@@ -71,25 +116,113 @@ func (mCommander *CommanderS) gMessageHandler(ws *websocket.Conn, GMessageArr []
 			if mCommander.neuron.Brain.Const.CommanderLog {
 				mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, fmt.Sprintf("%v -> GMessage[%v]", mCommander.neuron.Brain.Container.CommanderHub.Tag, ws.Request().RemoteAddr), 100, []interface{}{v.ID, v.Head, v.Tag, v.Cmds})
 			}
+			// 非主节点重定向至主节点
+			if !mCommander.IsLeader() {
+				mCommander.redirect(ws, v.ID)
+				return
+			}
+			// 未通过握手认证的节点仅允许HELLO/JOIN
+			if !mCommander.isJoined(ws, v) {
+				continue
+			}
+			// 秘钥须限定于该节点
+			if !mCommander.isScoped(ws, client.Tag, v) {
+				continue
+			}
 			switch v.Head {
 			case "!":
 				switch v.Tag {
+				case "HELLO":
+					// 协商编码格式,回复后生效
+					codec := mCommander.neuron.Express.WSCodecSelect(v.Cmds)
+					cmds := []interface{}{codec}
+					// 下发握手挑战码
+					challenge := ""
+					if mCommander.neuron.Brain.Const.Join.Open {
+						challenge = mCommander.neuron.Brain.UUID()
+						cmds = append(cmds, challenge)
+					}
+					if err := mCommander.neuron.Express.WSWrite(ws, &model.GMessageS{ID: v.ID, Head: "!", Tag: "HELLO", Cmds: cmds}); err != nil {
+						mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, "HELLO -> WSWrite", 214, err)
+						continue
+					}
+					mCommander.neuron.Express.WSConnUpdate(ws, func(state *model.ConnStateS) {
+						state.Codec = codec
+						state.Challenge = challenge
+					})
+				case "JOIN":
+					// 校验握手凭据并绑定节点编号
+					if !mCommander.join(ws, v) {
+						return
+					}
+					client.Tag = v.ID
+					mCommander.Container.nodeConns.Set(v.ID, ws)
 				case "HEART":
 					// 赋予tag信息为Const.NeuronId
 					client.Tag = v.ID
-					for _, vv := range v.Cmds {
-						// 用于其他模块获取心跳信息后更新数据
-						mCommander.Log(fmt.Sprintf("HEART -> [%v]", v.ID), mCommander.neuron.Brain.Base64Decoder(vv.(string)))
-						mCommander.neuron.Brain.Container.CommanderReply.Push(model.CommanderPiece{NeuronId: client.Tag, GMessage: *v})
+					mCommander.neuron.Brain.Container.CommanderHub.Set(ws.Request().RemoteAddr, client)
+					mCommander.Container.nodeConns.Set(v.ID, ws)
+					// 更新节点注册信息
+					mCommander.nodeHeart(client.Tag, ws.Request().RemoteAddr, v, mCommander.neuron.Express.WSConn(ws).NeuronId == v.ID)
+					// 补发离线期间暂存的指令
+					mCommander.pendingFlush(client.Tag)
+					mCommander.pendingRouteFlush(client.Tag)
+				case "ACK":
+					// 确认送达
+					if delivery, found := mCommander.neuron.Brain.Container.CommanderInflight.Pop(v.ID + "@" + client.Tag).(model.DeliveryS); found {
+						mCommander.neuron.Express.TraceHop(&delivery.Piece.GMessage, client.Tag, model.TraceAcked)
+					}
+				case "BUSY":
+					// 节点服务繁忙,额度置零并改派或暂存
+					service := ""
+					if len(v.Cmds) > 0 {
+						service, _ = v.Cmds[0].(string)
+					}
+					mCommander.creditSet(client.Tag, map[string]int{service: 0}, true)
+					if delivery, found := mCommander.neuron.Brain.Container.CommanderInflight.Pop(v.ID + "@" + client.Tag).(model.DeliveryS); found {
+						mCommander.neuron.Express.TraceHop(&delivery.Piece.GMessage, client.Tag, model.TraceBusy, service)
+						mCommander.creditDefer(client.Tag, delivery.Piece)
+					}
+				case "CREDIT":
+					// 节点额度恢复则补发暂存指令
+					credits := make(map[string]int)
+					if len(v.Cmds) > 0 && mCommander.neuron.Brain.JsonDecoder(mCommander.neuron.Brain.Base64Decoder(fmt.Sprint(v.Cmds[0])), &credits) != nil {
+						mCommander.creditSet(client.Tag, credits, false)
+						mCommander.pendingFlush(client.Tag)
+						mCommander.pendingRouteFlush(client.Tag)
+					}
+				case "TRACE":
+					// 节点上报的追踪阶段
+					mCommander.neuron.Express.TraceHandler(client.Tag, v)
+				case "ERROR":
+					mCommander.neuron.Express.TraceHop(v, client.Tag, model.TraceReplied, "ERROR")
+					// 远程调用被拒绝
+					if len(v.Cmds) > 0 {
+						mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, fmt.Sprintf("ERROR -> [%v]", client.Tag), 200, string(mCommander.neuron.Brain.Base64Decoder(fmt.Sprint(v.Cmds[0]))))
 					}
+					mCommander.callReject(client.Tag, v)
 				case "REPLY":
+					mCommander.neuron.Express.TraceHop(v, client.Tag, model.TraceReplied, "REPLY")
+					// 存在等待中的调用则直接交付
+					if mCommander.callResolve(client.Tag, v) {
+						continue
+					}
 					for _, vv := range v.Cmds {
-						mCommander.Log(fmt.Sprintf("REPLY -> [%v]", v.ID), mCommander.neuron.Brain.Base64Decoder(vv.(string)))
+						mCommander.Log(fmt.Sprintf("REPLY -> [%v]", v.ID), mCommander.neuron.Brain.Base64Decoder(fmt.Sprint(vv)))
 						mCommander.neuron.Brain.Container.CommanderReply.Push(model.CommanderPiece{NeuronId: client.Tag, GMessage: *v})
 					}
 				}
 			case "?":
 				if v.Tag == "EVAL" {
+					// 同ID反向EVAL视为调用回复
+					if mCommander.callResolve(client.Tag, v) {
+						continue
+					}
+					service, function, found := mCommander.neuron.Express.EvalTarget(v)
+					if !found {
+						mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, fmt.Sprintf("EVAL -> [%v]", client.Tag), 203, v.Cmds)
+						continue
+					}
 					var args []interface{}
 					args = append(args, client.Conn)
 					args = append(args, v.ID)
@@ -98,9 +231,17 @@ func (mCommander *CommanderS) gMessageHandler(ws *websocket.Conn, GMessageArr []
 							args = append(args, v)
 						}
 					}
-					trigger.FireBackground("EVAL", v.Cmds[0].(string), v.Cmds[1].(string), args)
+					trigger.FireBackground("EVAL", service, function, args, client.Tag)
 				}
 			case "~":
+				switch v.Tag {
+				case "OPEN", "DATA", "CLOSE", "ACK":
+					// 反向隧道数据
+					mCommander.neuron.Express.TunnelHandler(ws, v, false)
+				default:
+					// 分块文件传输
+					mCommander.neuron.Express.TransferHandler(ws, v, false)
+				}
 			}
 		}
 	}
@@ -110,11 +251,97 @@ func (mCommander *CommanderS) gMessageHandler(ws *websocket.Conn, GMessageArr []
 
 //* ================================ PRIVATE ================================ */
 
+//* 判断消息是否来自已认证节点 */
+func (mCommander *CommanderS) isJoined(ws *websocket.Conn, v *model.GMessageS) bool {
+	// 保留名称不可作为节点编号
+	if v.Head == "!" && (v.Tag == "HEART" || v.Tag == "JOIN") && v.ID == model.RPCCommander {
+		mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, "isJoined -> Reserved", 208, fmt.Sprintf("[%v] %v", ws.Request().RemoteAddr, v.ID))
+		return false
+	}
+	if !mCommander.neuron.Brain.Const.Join.Open {
+		return true
+	}
+	if v.Head == "!" && (v.Tag == "HELLO" || v.Tag == "JOIN") {
+		return true
+	}
+	neuronId := mCommander.neuron.Express.WSConn(ws).NeuronId
+	if neuronId == "" {
+		mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, "isJoined -> Unverified", 208, fmt.Sprintf("[%v] %v%v -> %v", ws.Request().RemoteAddr, v.Head, v.Tag, v.ID))
+		return false
+	}
+	// 心跳编号必须与认证身份一致
+	if v.Head == "!" && v.Tag == "HEART" && v.ID != neuronId {
+		mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, "isJoined -> Mismatch", 208, fmt.Sprintf("[%v] %v -> %v", ws.Request().RemoteAddr, neuronId, v.ID))
+		return false
+	}
+	return true
+}
+
+//* 校验连接所用秘钥是否允许该节点使用[身份依次取握手认证、心跳编号] */
+func (mCommander *CommanderS) isScoped(ws *websocket.Conn, tag string, v *model.GMessageS) bool {
+	state := mCommander.neuron.Express.WSConn(ws)
+	neuronId := state.NeuronId
+	if neuronId == "" {
+		neuronId = tag
+	}
+	if v.Head == "!" && (v.Tag == "HEART" || v.Tag == "JOIN") {
+		neuronId = v.ID
+	}
+	if neuronId == "" || mCommander.neuron.Brain.SecurityScoped(state.KeyId, neuronId) {
+		return true
+	}
+	mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, "isScoped -> KeyId", 208, fmt.Sprintf("[%v] %v -> %v", ws.Request().RemoteAddr, state.KeyId, neuronId))
+	return false
+}
+
+//* 校验握手凭据[失败则断开连接] */
+func (mCommander *CommanderS) join(ws *websocket.Conn, v *model.GMessageS) bool {
+	state := mCommander.neuron.Express.WSConn(ws)
+	credential, approved := mCommander.neuron.Brain.Const.Join.Approved[v.ID]
+	reason := ""
+	switch {
+	case !approved:
+		reason = "Not Approved"
+	case state.Challenge == "":
+		reason = "Lack of Challenge"
+	case len(v.Cmds) == 0:
+		reason = "Lack of Proof"
+	default:
+		proof, _ := v.Cmds[0].(string)
+		if !mCommander.neuron.Brain.JoinVerify(credential, state.Challenge, v.ID, proof) {
+			reason = "Invalid Proof"
+		}
+	}
+	if reason != "" {
+		mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, "join -> "+reason, 208, fmt.Sprintf("[%v] %v", ws.Request().RemoteAddr, v.ID))
+		mCommander.neuron.Express.WSWrite(ws, &model.GMessageS{ID: v.ID, Head: "!", Tag: "JOIN", Cmds: []interface{}{"denied"}})
+		ws.Close()
+		return false
+	}
+	// 挑战码仅可使用一次
+	mCommander.neuron.Express.WSConnUpdate(ws, func(state *model.ConnStateS) {
+		state.Challenge = ""
+		state.NeuronId = v.ID
+	})
+	mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, "join -> Verified", 100, fmt.Sprintf("[%v] %v", ws.Request().RemoteAddr, v.ID))
+	if err := mCommander.neuron.Express.WSWrite(ws, &model.GMessageS{ID: v.ID, Head: "!", Tag: "JOIN", Cmds: []interface{}{"ok"}}); err != nil {
+		mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, "join -> WSWrite", 214, err)
+	}
+	return true
+}
+
 //* 注册服务 */
 func (mCommander *CommanderS) main() {
+	mCommander.router = mCommander.neuron.Express.Group(mCommander.mux, mCommander.Const.root, mCommander)
 	/* 初始化通信协议 */
 	mCommander.commandChannelInit()
 	mCommander.commandMessageInterface()
+	mCommander.deadLetterInterface()
+	mCommander.nodesInterface()
+	mCommander.gatherInterface()
+	mCommander.leaderInterface()
+	mCommander.transferInterface()
+	mCommander.traceInterface()
 }
 
 //* ================================ INTERFACE ================================ */
@@ -122,27 +349,180 @@ func (mCommander *CommanderS) main() {
 //* 指令发送接口 */
 func (mCommander *CommanderS) commandMessageInterface() {
 	// Interface Init
+	mCommander.neuron.Express.UseService(mCommander.Const.root+"/Message", mCommander.neuron.Express.Auth("commander"))
 	mCommander.mux.HandleFunc(mCommander.Const.root+"/Message", func(res http.ResponseWriter, req *http.Request) {
 		mCommander.neuron.Express.ConstructInterface(res, req, mCommander.isStarted, func() {
-			query := mCommander.neuron.Express.Req2Query(req)
-			if mCommander.neuron.Brain.CheckIsNull(query) {
-				mCommander.neuron.Express.CodeResponse(res, 207, "Lack of Parameter", "commandMessageInterface")
+			var param struct {
+				NeuronId string `validate:"required"`
+				Message  string `validate:"required"`
+				// 可选优先级及延迟毫秒数
+				Priority int `validate:"min=-1,max=2"`
+				Delay    int `validate:"min=0"`
+			}
+			if code, data := mCommander.neuron.Express.Bind(req, &param); code != 100 {
+				mCommander.neuron.Express.CodeResponse(res, code, data, "commandMessageInterface")
 				return
 			}
-			neuronId := query["neuronId"][0]
-			if mCommander.neuron.Brain.CheckIsNull(neuronId) {
-				mCommander.neuron.Express.CodeResponse(res, 207, "Lack of Parameter -> neuronId", "commandMessageInterface")
+			gMsg := mCommander.neuron.Brain.AnalyzeMessage(param.Message)
+			if len(gMsg) == 0 {
+				mCommander.neuron.Express.CodeResponse(res, 207, []model.FieldErrorS{{Field: "message", Rule: "format", Message: "must be a GMessage"}}, "commandMessageInterface")
 				return
 			}
-			message := query["message"][0]
-			if mCommander.neuron.Brain.CheckIsNull(message) {
-				mCommander.neuron.Express.CodeResponse(res, 207, "Lack of Parameter -> message", "commandMessageInterface")
+			piece := model.CommanderPiece{NeuronId: param.NeuronId, GMessage: *gMsg[0], Priority: param.Priority}
+			if param.Delay > 0 {
+				piece.NotBefore = time.Now().Add(time.Duration(param.Delay) * time.Millisecond)
+			}
+			mCommander.neuron.Express.CommanderPush(piece)
+			mCommander.neuron.Express.CodeResponse(res, 100)
+		})
+	})
+}
+
+//* 集群主节点接口 */
+func (mCommander *CommanderS) leaderInterface() {
+	mCommander.neuron.Express.UseService(mCommander.Const.root+"/Leader", mCommander.neuron.Express.Auth("commander", "monitor"))
+	mCommander.mux.HandleFunc(mCommander.Const.root+"/Leader", func(res http.ResponseWriter, req *http.Request) {
+		mCommander.neuron.Express.ConstructInterface(res, req, mCommander.isStarted, func() {
+			mCommander.neuron.Express.CodeResponse(res, 100, map[string]interface{}{
+				"Self":     mCommander.advertise(),
+				"Leader":   mCommander.Leader(),
+				"IsLeader": mCommander.IsLeader(),
+			})
+		})
+	})
+}
+
+//* 文件传输接口[?push=&route= -> 推送至节点 | ?pull=&neuronId= -> 从节点拉取] */
+func (mCommander *CommanderS) transferInterface() {
+	mCommander.neuron.Express.UseService(mCommander.Const.root+"/Transfer", mCommander.neuron.Express.Auth("commander"))
+	mCommander.mux.HandleFunc(mCommander.Const.root+"/Transfer", func(res http.ResponseWriter, req *http.Request) {
+		mCommander.neuron.Express.ConstructInterface(res, req, mCommander.isStarted, func() {
+			var param struct {
+				Push     string
+				Route    string
+				Pull     string
+				NeuronId string
+			}
+			if code, data := mCommander.neuron.Express.Bind(req, &param); code != 100 {
+				mCommander.neuron.Express.CodeResponse(res, code, data, "transferInterface")
+				return
+			}
+			if !mCommander.neuron.Brain.CheckIsNull(param.Push) {
+				mCommander.neuron.Express.CodeResponse(res, 100, mCommander.neuron.Express.TransferPush(param.Route, param.Push), "transferInterface")
+				return
+			}
+			errs := make([]model.FieldErrorS, 0)
+			if mCommander.neuron.Brain.CheckIsNull(param.Pull) {
+				errs = append(errs, model.FieldErrorS{Field: "push | pull", Rule: "required", Message: "is required"})
+			} else if mCommander.neuron.Brain.CheckIsNull(param.NeuronId) {
+				errs = append(errs, model.FieldErrorS{Field: "neuronId", Rule: "required", Message: "is required"})
+			}
+			if len(errs) > 0 {
+				mCommander.neuron.Express.CodeResponse(res, 207, errs, "transferInterface")
 				return
 			}
+			result := mCommander.neuron.Express.TransferPull(param.NeuronId, param.Pull)
+			mCommander.neuron.Express.CodeResponse(res, result.Code, result, "transferInterface")
+		})
+	})
+}
 
-			gMsg := mCommander.neuron.Brain.AnalyzeMessage(message)
-			mCommander.neuron.Brain.Container.CommanderQueue.Push(model.CommanderPiece{NeuronId: neuronId, GMessage: *gMsg[0]})
-			mCommander.neuron.Express.CodeResponse(res, 100)
+//* 指令追踪接口[?id=追踪编号或指令编号] */
+func (mCommander *CommanderS) traceInterface() {
+	mCommander.neuron.Express.UseService(mCommander.Const.root+"/Trace", mCommander.neuron.Express.Auth("commander", "monitor"))
+	mCommander.mux.HandleFunc(mCommander.Const.root+"/Trace", func(res http.ResponseWriter, req *http.Request) {
+		mCommander.neuron.Express.ConstructInterface(res, req, mCommander.isStarted, func() {
+			var param struct {
+				Id string `validate:"required"`
+			}
+			if code, data := mCommander.neuron.Express.Bind(req, &param); code != 100 {
+				mCommander.neuron.Express.CodeResponse(res, code, data, "traceInterface")
+				return
+			}
+			hops := mCommander.neuron.Express.Trace(param.Id)
+			if len(hops) == 0 {
+				mCommander.neuron.Express.CodeResponse(res, 220, "Trace not Found -> "+param.Id, "traceInterface")
+				return
+			}
+			mCommander.neuron.Express.CodeResponse(res, 100, hops, "traceInterface")
+		})
+	})
+}
+
+//* 死信队列接口 */
+func (mCommander *CommanderS) deadLetterInterface() {
+	mCommander.neuron.Express.UseService(mCommander.Const.root+"/DeadLetter", mCommander.neuron.Express.Auth("commander"))
+	mCommander.mux.HandleFunc(mCommander.Const.root+"/DeadLetter", func(res http.ResponseWriter, req *http.Request) {
+		mCommander.neuron.Express.ConstructInterface(res, req, mCommander.isStarted, func() {
+			query := mCommander.neuron.Express.Req2Query(req)
+			deadLetter := mCommander.neuron.Brain.Container.CommanderDeadLetter
+			// 重新投递全部死信
+			if _, found := query["retry"]; found {
+				count := 0
+				for !deadLetter.IsEmpty() {
+					delivery, found := deadLetter.Shift().(model.DeliveryS)
+					if !found {
+						continue
+					}
+					mCommander.requeue(delivery.Piece, false)
+					count++
+				}
+				mCommander.neuron.Express.CodeResponse(res, 100, count, "deadLetterInterface")
+				return
+			}
+			mCommander.neuron.Express.CodeResponse(res, 100, deadLetter.ToArrayV(), "deadLetterInterface")
+		})
+	})
+}
+
+//* 节点注册信息接口[GET /Nodes?state= | GET /Nodes/{id}] */
+func (mCommander *CommanderS) nodesInterface() {
+	nodeResponse := func(res http.ResponseWriter, neuronId string) {
+		node, found := mCommander.neuron.Brain.Container.CommanderNodes.Get(neuronId).(*model.NodeS)
+		if !found {
+			mCommander.neuron.Express.CodeResponse(res, 220, "Node not Found -> "+neuronId, "nodesInterface")
+			return
+		}
+		mCommander.neuron.Express.CodeResponse(res, 100, *node, "nodesInterface")
+	}
+	auth := mCommander.neuron.Express.Auth("commander", "monitor")
+	mCommander.router.GET("/Nodes", func(res http.ResponseWriter, req *http.Request) {
+		query := mCommander.neuron.Express.Req2Query(req)
+		// 兼容?neuronId=
+		if neuronId, found := query["neuronId"]; found {
+			nodeResponse(res, neuronId[0])
+			return
+		}
+		mCommander.neuron.Express.CodeResponse(res, 100, mCommander.neuron.Express.Nodes(query["state"]...), "nodesInterface")
+	}, auth)
+	mCommander.router.GET("/Nodes/{id}", func(res http.ResponseWriter, req *http.Request) {
+		nodeResponse(res, mCommander.neuron.Express.Param(req, "id"))
+	}, auth)
+}
+
+//* 广播汇总接口[?route=&service=&function=&quorum=&timeout=&param=] */
+func (mCommander *CommanderS) gatherInterface() {
+	mCommander.neuron.Express.UseService(mCommander.Const.root+"/Gather", mCommander.neuron.Express.Auth("commander"))
+	mCommander.mux.HandleFunc(mCommander.Const.root+"/Gather", func(res http.ResponseWriter, req *http.Request) {
+		mCommander.neuron.Express.ConstructInterface(res, req, mCommander.isStarted, func() {
+			var param struct {
+				Route    string
+				Service  string `validate:"required"`
+				Function string `validate:"required"`
+				Quorum   int    `validate:"min=0"`
+				Timeout  int    `validate:"min=0"`
+				Param    []string
+			}
+			if code, data := mCommander.neuron.Express.Bind(req, &param); code != 100 {
+				mCommander.neuron.Express.CodeResponse(res, code, data, "gatherInterface")
+				return
+			}
+			var params [][]byte
+			for _, v := range param.Param {
+				params = append(params, []byte(v))
+			}
+			gather := mCommander.neuron.Express.CommanderGather(param.Route, param.Service, param.Function, param.Quorum, param.Timeout, params...)
+			mCommander.neuron.Express.CodeResponse(res, 100, gather, "gatherInterface")
 		})
 	})
 }
@@ -153,10 +533,37 @@ func (mCommander *CommanderS) commandMessageInterface() {
 func (mCommander *CommanderS) commandChannelInit() {
 	// Container Init
 	mCommander.neuron.Brain.Container.CommanderHub.Init("CommanderChannel")
-	// Queue Init
-	mCommander.neuron.Brain.Container.CommanderQueue = new(model.QueueS).New()
+	mCommander.neuron.Brain.Container.CommanderNodes.Init("CommanderNodes")
+	mCommander.Container.nodeConns.Init("CommanderNodeConns")
+	// Queue Init[集群模式下共享]
+	if store := mCommander.neuron.Brain.Container.ClusterStore; store != nil {
+		mCommander.neuron.Brain.Container.CommanderQueue = new(model.StoreQueueS).New(store, mCommander.clusterKey("CommanderQueue"), mCommander.pieceCodec())
+		mCommander.neuron.Brain.Container.CommanderPriority = new(model.StoreQueueS).New(store, mCommander.clusterKey("CommanderPriority"), mCommander.pieceCodec())
+	} else {
+		mCommander.neuron.Brain.Container.CommanderQueue = mCommander.queueInit("CommanderQueue", mCommander.pieceCodec())
+		mCommander.neuron.Brain.Container.CommanderPriority = mCommander.queueInit("CommanderPriority", mCommander.pieceCodec())
+	}
 	// Reply Init
-	mCommander.neuron.Brain.Container.CommanderReply = new(model.QueueS).New(1 << 20)
+	mCommander.neuron.Brain.Container.CommanderReply = mCommander.queueInit("CommanderReply", mCommander.pieceCodec(), 1<<20)
+	// Call Init[等待方位于本进程,不共享]
+	mCommander.neuron.Brain.Container.CommanderCall.Init("CommanderCall")
+	// Delivery Init[集群模式下共享,主节点切换后继续重发]
+	mCommander.neuron.Brain.Container.CommanderInflight = mCommander.mapInit("CommanderInflight", mCommander.deliveryCodec())
+	mCommander.neuron.Brain.Container.CommanderPending.Init("CommanderPending")
+	if store := mCommander.neuron.Brain.Container.ClusterStore; store != nil {
+		mCommander.neuron.Brain.Container.CommanderDeadLetter = new(model.StoreQueueS).New(store, mCommander.clusterKey("CommanderDeadLetter"), mCommander.deliveryCodec())
+	} else {
+		mCommander.neuron.Brain.Container.CommanderDeadLetter = mCommander.queueInit("CommanderDeadLetter", mCommander.deliveryCodec(), mCommander.neuron.Brain.Const.CommanderParam.DeadLetterLen)
+	}
+	// Schedule Init
+	mCommander.Container.sendQueues = make(map[string]*nodeSendS)
+	mCommander.Container.delayed = new(model.DelayQueueS).New()
+	mCommander.Container.delayedHub = mCommander.mapInit("CommanderDelayed", mCommander.pieceCodec())
+	mCommander.Container.staged = mCommander.mapInit("CommanderStaged", mCommander.pieceCodec())
+	// 集群模式由主节点接管时恢复
+	if mCommander.neuron.Brain.Container.ClusterStore == nil {
+		mCommander.scheduleLoad()
+	}
 	// Interface Init
 	mCommander.mux.HandleFunc(mCommander.Const.root+"/Channel", func(res http.ResponseWriter, req *http.Request) {
 		mCommander.neuron.Express.ConstructInterface(res, req, mCommander.isStarted, func() {
@@ -179,10 +586,30 @@ func (mCommander *CommanderS) commanderLooper() {
 		if !mCommander.isStarted {
 			return 103, "commanderLooper -> Shutdown"
 		}
-		// Unshift CommanderQueue
-		if !mCommander.neuron.Brain.Container.CommanderQueue.IsEmpty() {
-			CommanderPiece := mCommander.neuron.Brain.Container.CommanderQueue.Shift()
-			mCommander.sendCommand(CommanderPiece)
+		// 仅主节点发布指令
+		if !mCommander.IsLeader() {
+			return 100, nil
+		}
+		// 到期的延迟指令
+		now := time.Now()
+		for _, v := range mCommander.Container.delayed.PopDue(now) {
+			item := v.(scheduleItemS)
+			mCommander.sendCommand(item.piece)
+			mCommander.Container.delayedHub.Del(item.key)
+		}
+		// 分发至节点发送队列[CommanderPriority优先],超出积压上限则暂留于队列
+		for _, queue := range []model.QueueI{mCommander.neuron.Brain.Container.CommanderPriority, mCommander.neuron.Brain.Container.CommanderQueue} {
+			for atomic.LoadInt64(&mCommander.Container.sendBacklog) < int64(mCommander.neuron.Brain.Const.CommanderParam.SendBacklog) && !queue.IsEmpty() {
+				piece, found := queue.Shift().(model.CommanderPiece)
+				if !found {
+					continue
+				}
+				if piece.NotBefore.After(now) {
+					mCommander.delayPush(piece)
+					continue
+				}
+				mCommander.sendCommand(piece)
+			}
 		}
 		return 100, nil
 	}, func(code int, data interface{}) {
@@ -196,27 +623,867 @@ func (mCommander *CommanderS) commanderLooperKiller() {
 	mCommander.neuron.Brain.ClearInterval(mCommander.StopChannel.CommanderLooperSC)
 }
 
+//* 重发未确认指令 */
+func (mCommander *CommanderS) deliveryLooper() {
+	mCommander.StopChannel.deliveryLooperSC = make(chan bool)
+	go mCommander.neuron.Brain.SetInterval(func() (int, interface{}) {
+		if !mCommander.isStarted {
+			return 103, "deliveryLooper -> Shutdown"
+		}
+		// 仅主节点重发[集群模式下投递中的指令共享]
+		if !mCommander.IsLeader() {
+			return 100, nil
+		}
+		now := time.Now()
+		inflight := mCommander.neuron.Brain.Container.CommanderInflight
+		due := make([]string, 0)
+		inflight.Iterator(func(n int, k string, v interface{}) bool {
+			if delivery, found := v.(model.DeliveryS); !found || !now.Before(delivery.NextRetry) {
+				due = append(due, k)
+			}
+			return true
+		})
+		for _, k := range due {
+			delivery, found := inflight.Pop(k).(model.DeliveryS)
+			if !found {
+				continue
+			}
+			// 超过重发次数则进入死信队列
+			if delivery.Attempts > mCommander.neuron.Brain.Const.CommanderParam.MaxRetry {
+				mCommander.deadLetterPush(delivery, "MaxRetry")
+				continue
+			}
+			// 节点离线则暂存
+			conn := mCommander.nodeConn(delivery.NeuronId)
+			if conn == nil {
+				mCommander.pendingPush(delivery.NeuronId, delivery.Piece)
+				continue
+			}
+			mCommander.deliver(delivery.Piece, delivery.NeuronId, conn, delivery.Attempts)
+		}
+		return 100, nil
+	}, func(code int, data interface{}) {
+		if code != 100 {
+			mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, "deliveryLooper -> Error", code, data)
+		}
+	}, mCommander.neuron.Brain.Const.Interval.HZ1Interval, mCommander.StopChannel.deliveryLooperSC)
+}
+
+func (mCommander *CommanderS) deliveryLooperKiller() {
+	mCommander.neuron.Brain.ClearInterval(mCommander.StopChannel.deliveryLooperSC)
+}
+
+//* 清理超时调用 */
+func (mCommander *CommanderS) callLooper() {
+	mCommander.StopChannel.callLooperSC = make(chan bool)
+	go mCommander.neuron.Brain.SetInterval(func() (int, interface{}) {
+		if !mCommander.isStarted {
+			return 103, "callLooper -> Shutdown"
+		}
+		now := time.Now()
+		expired := make([]string, 0)
+		mCommander.neuron.Brain.Container.CommanderCall.Iterator(func(n int, k string, v interface{}) bool {
+			if call, found := v.(*model.CallS); !found || call.IsExpired(now) {
+				expired = append(expired, k)
+			}
+			return true
+		})
+		for _, k := range expired {
+			if call, found := mCommander.neuron.Brain.Container.CommanderCall.Pop(k).(*model.CallS); found {
+				call.Resolve(104, nil)
+			}
+		}
+		return 100, nil
+	}, func(code int, data interface{}) {
+		if code != 100 {
+			mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, "callLooper -> Error", code, data)
+		}
+	}, mCommander.neuron.Brain.Const.Interval.HZ1Interval, mCommander.StopChannel.callLooperSC)
+}
+
+func (mCommander *CommanderS) callLooperKiller() {
+	mCommander.neuron.Brain.ClearInterval(mCommander.StopChannel.callLooperSC)
+	// 取消全部等待中的调用
+	for _, k := range mCommander.neuron.Brain.Container.CommanderCall.Key2Slice() {
+		if call, found := mCommander.neuron.Brain.Container.CommanderCall.Pop(k).(*model.CallS); found {
+			call.Resolve(103, nil)
+		}
+	}
+}
+
+//* 节点存活检测 */
+func (mCommander *CommanderS) nodeLooper() {
+	mCommander.StopChannel.nodeLooperSC = make(chan bool)
+	go mCommander.neuron.Brain.SetInterval(func() (int, interface{}) {
+		if !mCommander.isStarted {
+			return 103, "nodeLooper -> Shutdown"
+		}
+		now := time.Now()
+		// 与WSHandler读取超时一致
+		heart := time.Duration(mCommander.neuron.Brain.Const.WSParam.Interval+3000) * time.Millisecond
+		for _, k := range mCommander.neuron.Brain.Container.CommanderNodes.Key2Slice() {
+			node, found := mCommander.neuron.Brain.Container.CommanderNodes.Get(k).(*model.NodeS)
+			if !found || node.State == "dead" {
+				continue
+			}
+			missed := int(now.Sub(node.LastHeart) / heart)
+			switch {
+			case missed >= mCommander.neuron.Brain.Const.CommanderParam.DeadMiss || mCommander.nodeConn(k) == nil:
+				mCommander.nodeUpdate(k, func(latest *model.NodeS) string {
+					// 期间收到心跳则放弃
+					if !latest.LastHeart.Equal(node.LastHeart) {
+						return ""
+					}
+					latest.Missed = missed
+					latest.State = "dead"
+					return "NODE_LEAVE"
+				})
+			case missed >= mCommander.neuron.Brain.Const.CommanderParam.SuspectMiss && node.State == "alive":
+				mCommander.nodeUpdate(k, func(latest *model.NodeS) string {
+					// 期间收到心跳则放弃
+					if !latest.LastHeart.Equal(node.LastHeart) {
+						return ""
+					}
+					latest.Missed = missed
+					latest.State = "suspect"
+					return "NODE_SUSPECT"
+				})
+			}
+		}
+		return 100, nil
+	}, func(code int, data interface{}) {
+		if code != 100 {
+			mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, "nodeLooper -> Error", code, data)
+		}
+	}, mCommander.neuron.Brain.Const.Interval.HZ1Interval, mCommander.StopChannel.nodeLooperSC)
+}
+
+func (mCommander *CommanderS) nodeLooperKiller() {
+	mCommander.neuron.Brain.ClearInterval(mCommander.StopChannel.nodeLooperSC)
+}
+
+//* 集群选主[抢占或续约主节点租约] */
+func (mCommander *CommanderS) electionLooper() {
+	if mCommander.neuron.Brain.Container.ClusterStore == nil {
+		return
+	}
+	mCommander.StopChannel.electionLooperSC = make(chan bool)
+	go mCommander.neuron.Brain.SetInterval(func() (int, interface{}) {
+		if !mCommander.isStarted {
+			return 103, "electionLooper -> Shutdown"
+		}
+		store := mCommander.neuron.Brain.Container.ClusterStore
+		key := mCommander.clusterKey("leader")
+		self := mCommander.advertise()
+		ttl := mCommander.neuron.Brain.Const.Cluster.LeaseTTL
+		// 租约自请求发出时起算
+		expire := time.Now().Add(time.Duration(ttl) * time.Millisecond)
+		mCommander.Container.leaderMutex.RLock()
+		wasLeader := mCommander.Container.isLeader
+		mCommander.Container.leaderMutex.RUnlock()
+		var elected bool
+		var err error
+		if wasLeader {
+			elected, err = store.Renew(key, self, ttl)
+		} else {
+			elected, err = store.Acquire(key, self, ttl)
+		}
+		// 共享存储异常则主动让出并尝试释放租约,避免多主
+		if err != nil {
+			mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, "electionLooper -> Store", 401, err)
+			if wasLeader {
+				if err := store.Release(key, self); err != nil {
+					mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, "electionLooper -> Release", 401, err)
+				}
+			}
+			mCommander.leaderSet(false, "", time.Time{})
+			return 100, nil
+		}
+		leader, err := store.Get(key)
+		if err != nil {
+			mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, "electionLooper -> Get", 401, err)
+		}
+		// 未当选时忽略自身残留租约,避免重定向至自身
+		if !elected && leader == self {
+			leader = ""
+		}
+		if !elected {
+			expire = time.Time{}
+		}
+		mCommander.leaderSet(elected, leader, expire)
+		return 100, nil
+	}, func(code int, data interface{}) {
+		if code != 100 {
+			mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, "electionLooper -> Error", code, data)
+		}
+	}, mCommander.neuron.Brain.Const.Cluster.LeaseTTL/3, mCommander.StopChannel.electionLooperSC)
+}
+
+func (mCommander *CommanderS) electionLooperKiller() {
+	if mCommander.neuron.Brain.Container.ClusterStore == nil {
+		return
+	}
+	mCommander.neuron.Brain.ClearInterval(mCommander.StopChannel.electionLooperSC)
+	// 主动释放租约以加快切换
+	if mCommander.IsLeader() {
+		if err := mCommander.neuron.Brain.Container.ClusterStore.Release(mCommander.clusterKey("leader"), mCommander.advertise()); err != nil {
+			mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, "electionLooperKiller -> Release", 401, err)
+		}
+	}
+	mCommander.leaderSet(false, "", time.Time{})
+}
+
 //* ================================ TOOL ================================ */
 
+//* 集群共享存储键 */
+func (mCommander *CommanderS) clusterKey(name string) string {
+	return mCommander.neuron.Brain.Const.Cluster.Prefix + ":" + name
+}
+
+//* 本Commander对外地址 */
+func (mCommander *CommanderS) advertise() string {
+	if advertise := mCommander.neuron.Brain.Const.Cluster.Advertise; advertise != "" {
+		return advertise
+	}
+	return mCommander.neuron.Brain.Const.CommanderHost
+}
+
+//* 更新主节点状态[expire -> 租约到期时间] */
+func (mCommander *CommanderS) leaderSet(isLeader bool, leader string, expire time.Time) {
+	mCommander.Container.leaderMutex.Lock()
+	wasLeader := mCommander.Container.isLeader
+	mCommander.Container.isLeader = isLeader
+	mCommander.Container.leader = leader
+	mCommander.Container.leaseExpire = expire
+	mCommander.Container.leaderMutex.Unlock()
+	switch {
+	case isLeader && !wasLeader:
+		mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, "leaderSet -> Promoted", 100, mCommander.advertise())
+		mCommander.pendingLoad()
+		mCommander.scheduleLoad()
+		mCommander.inflightLoad()
+	case !isLeader && wasLeader:
+		mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, "leaderSet -> Demoted", 100, leader)
+		// 断开全部Receiver,由其重连至新主节点
+		for _, v := range mCommander.neuron.Brain.Container.CommanderHub.Val2Slice() {
+			if client, found := v.(model.SocketClient); found {
+				client.Conn.(*websocket.Conn).Close()
+			}
+		}
+	}
+}
+
+//* 重定向至主节点[!REDIRECT -> 主节点地址] */
+func (mCommander *CommanderS) redirect(ws *websocket.Conn, msgId string) {
+	leader := mCommander.Leader()
+	if leader == "" {
+		mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, "redirect -> Lack of Leader", 200, ws.Request().RemoteAddr)
+	} else if err := mCommander.neuron.Express.WSWrite(ws, &model.GMessageS{ID: msgId, Head: "!", Tag: "REDIRECT", Cmds: []interface{}{leader}}); err != nil {
+		mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, "redirect -> WSWrite", 214, err)
+	}
+	ws.Close()
+}
+
+//* 心跳更新节点注册信息[Cmds[0] -> Base64(JSON(NodeS))] */
+func (mCommander *CommanderS) nodeHeart(neuronId string, remoteAddr string, gMsg *model.GMessageS, verified bool) {
+	if mCommander.neuron.Brain.CheckIsNull(neuronId) {
+		return
+	}
+	info := new(model.NodeS)
+	if len(gMsg.Cmds) > 0 {
+		if cmd, found := gMsg.Cmds[0].(string); found {
+			mCommander.neuron.Brain.JsonDecoder(mCommander.neuron.Brain.Base64Decoder(cmd), info)
+		}
+	}
+	now := time.Now()
+	mCommander.nodeUpdate(neuronId, func(node *model.NodeS) string {
+		event := ""
+		if node.State != "alive" && node.State != "suspect" {
+			// 新节点或死亡后重连
+			event = "NODE_JOIN"
+			node.ConnectTime = now
+		} else if node.RemoteAddr != remoteAddr {
+			node.ConnectTime = now
+		}
+		node.NeuronId = neuronId
+		node.RemoteAddr = remoteAddr
+		node.State = "alive"
+		node.LastHeart = now
+		node.Missed = 0
+		node.Verified = verified
+		if !mCommander.neuron.Brain.CheckIsNull(info.Version) {
+			node.Version = info.Version
+			node.Services = info.Services
+			node.Labels = info.Labels
+			node.Metric = info.Metric
+		}
+		return event
+	})
+}
+
+//* 写时复制更新节点信息[update返回事件名则触发NODE事件] */
+func (mCommander *CommanderS) nodeUpdate(neuronId string, update func(node *model.NodeS) string) {
+	mCommander.Container.nodeMutex.Lock()
+	node := new(model.NodeS)
+	if old, found := mCommander.neuron.Brain.Container.CommanderNodes.Get(neuronId).(*model.NodeS); found {
+		*node = *old
+	}
+	event := update(node)
+	mCommander.neuron.Brain.Container.CommanderNodes.Set(neuronId, node)
+	mCommander.Container.nodeMutex.Unlock()
+	if event == "" {
+		return
+	}
+	mCommander.Log(event, fmt.Sprintf("[%s] -> %s", neuronId, node.RemoteAddr))
+	trigger.FireBackground("NODE", event, *node)
+}
+
+//* 初始化队列[开启持久化则重放/data下的预写日志] */
+func (mCommander *CommanderS) queueInit(name string, codec model.PersistCodecS, maxLen ...int) model.QueueI {
+	if mCommander.neuron.Brain.Const.Persistence.Commander {
+		code, data := mCommander.neuron.Brain.PersistQueue(name, codec, maxLen...)
+		if code == 100 {
+			return data.(model.QueueI)
+		}
+		mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, "queueInit[PersistQueue]", code, data)
+	}
+	return new(model.QueueS).New(maxLen...)
+}
+
+//* 初始化调度容器[集群模式下共享,否则按Persistence.Commander持久化] */
+func (mCommander *CommanderS) mapInit(name string, codec model.PersistCodecS) model.MapI {
+	if store := mCommander.neuron.Brain.Container.ClusterStore; store != nil {
+		return new(model.StoreMapS).New(store, mCommander.clusterKey(name), codec)
+	}
+	if mCommander.neuron.Brain.Const.Persistence.Commander {
+		code, data := mCommander.neuron.Brain.PersistMap(name, codec)
+		if code == 100 {
+			return data.(model.MapI)
+		}
+		mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, "mapInit[PersistMap]", code, data)
+	}
+	hub := new(model.SyncMapHub)
+	hub.Init(name)
+	return hub
+}
+
+//* CommanderPiece编解码[NeuronId(\t优先级\t发送时间毫秒) + 换行 + 二进制GMessage,兼容文本格式] */
+func (mCommander *CommanderS) pieceCodec() model.PersistCodecS {
+	return model.PersistCodecS{
+		Encode: func(v interface{}) ([]byte, error) {
+			piece, found := v.(model.CommanderPiece)
+			if !found {
+				return nil, fmt.Errorf("pieceCodec -> DataType Error")
+			}
+			var buf bytes.Buffer
+			buf.WriteString(piece.NeuronId)
+			if piece.Priority != model.PriorityNormal || !piece.NotBefore.IsZero() {
+				notBefore := int64(0)
+				if !piece.NotBefore.IsZero() {
+					notBefore = piece.NotBefore.UnixNano() / int64(time.Millisecond)
+				}
+				buf.WriteString(fmt.Sprintf("\t%d\t%d", piece.Priority, notBefore))
+			}
+			buf.WriteString("\n")
+			buf.Write(mCommander.neuron.Brain.EncodeMessage(model.CodecBinary, &piece.GMessage))
+			return buf.Bytes(), nil
+		},
+		Decode: func(b []byte) (interface{}, error) {
+			index := bytes.IndexByte(b, '\n')
+			if index == -1 {
+				return nil, fmt.Errorf("pieceCodec -> Format Error")
+			}
+			gMsg := mCommander.neuron.Brain.DecodeMessage(b[index+1:])
+			if len(gMsg) == 0 || mCommander.neuron.Brain.CheckIsNull(gMsg[0]) {
+				return nil, fmt.Errorf("pieceCodec -> GMessage Error")
+			}
+			piece := model.CommanderPiece{GMessage: *gMsg[0]}
+			fields := strings.Split(string(b[:index]), "\t")
+			piece.NeuronId = fields[0]
+			if len(fields) == 3 {
+				piece.Priority, _ = strconv.Atoi(fields[1])
+				if notBefore, _ := strconv.ParseInt(fields[2], 10, 64); notBefore > 0 {
+					piece.NotBefore = time.Unix(0, notBefore*int64(time.Millisecond))
+				}
+			}
+			return piece, nil
+		},
+	}
+}
+
+//* DeliveryS编解码[NeuronId\t发送次数\t下次重发时间毫秒 + 换行 + CommanderPiece] */
+func (mCommander *CommanderS) deliveryCodec() model.PersistCodecS {
+	pieceCodec := mCommander.pieceCodec()
+	return model.PersistCodecS{
+		Encode: func(v interface{}) ([]byte, error) {
+			delivery, found := v.(model.DeliveryS)
+			if !found {
+				return nil, fmt.Errorf("deliveryCodec -> DataType Error")
+			}
+			piece, err := pieceCodec.Encode(delivery.Piece)
+			if err != nil {
+				return nil, err
+			}
+			var buf bytes.Buffer
+			buf.WriteString(fmt.Sprintf("%s\t%d\t%d\n", delivery.NeuronId, delivery.Attempts, delivery.NextRetry.UnixNano()/int64(time.Millisecond)))
+			buf.Write(piece)
+			return buf.Bytes(), nil
+		},
+		Decode: func(b []byte) (interface{}, error) {
+			index := bytes.IndexByte(b, '\n')
+			if index == -1 {
+				return nil, fmt.Errorf("deliveryCodec -> Format Error")
+			}
+			fields := strings.Split(string(b[:index]), "\t")
+			if len(fields) != 3 {
+				return nil, fmt.Errorf("deliveryCodec -> Format Error")
+			}
+			piece, err := pieceCodec.Decode(b[index+1:])
+			if err != nil {
+				return nil, err
+			}
+			delivery := model.DeliveryS{NeuronId: fields[0], Piece: piece.(model.CommanderPiece)}
+			delivery.Attempts, _ = strconv.Atoi(fields[1])
+			nextRetry, _ := strconv.ParseInt(fields[2], 10, 64)
+			delivery.NextRetry = time.Unix(0, nextRetry*int64(time.Millisecond))
+			return delivery, nil
+		},
+	}
+}
+
+//* 交付调用结果 */
+func (mCommander *CommanderS) callResolve(neuronId string, gMsg *model.GMessageS) bool {
+	if mCommander.neuron.Brain.CheckIsNull(gMsg.ID) {
+		return false
+	}
+	call := mCommander.callOwned(neuronId, gMsg.ID)
+	if call == nil {
+		return false
+	}
+	return call.Resolve(100, &model.CommanderPiece{NeuronId: neuronId, GMessage: *gMsg})
+}
+
+//* 取出节点自身的调用凭证[回复节点须为调用目标或匹配其路由,否则忽略] */
+func (mCommander *CommanderS) callOwned(neuronId string, msgId string) *model.CallS {
+	call, found := mCommander.neuron.Brain.Container.CommanderCall.Get(msgId).(*model.CallS)
+	if !found {
+		return nil
+	}
+	route := new(model.RouteS).Parse(call.NeuronId)
+	owned := route.NeuronId == neuronId
+	if !route.IsExact() {
+		node, found := mCommander.neuron.Brain.Container.CommanderNodes.Get(neuronId).(*model.NodeS)
+		owned = found && route.Match(*node)
+	}
+	if !owned {
+		mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, "callOwned -> Foreign Reply", 208, fmt.Sprintf("[%s] -> %s", neuronId, msgId))
+		return nil
+	}
+	call, found = mCommander.neuron.Brain.Container.CommanderCall.Pop(msgId).(*model.CallS)
+	if !found {
+		return nil
+	}
+	return call
+}
+
+//* 远程调用被拒绝[调用结果为错误码] */
+func (mCommander *CommanderS) callReject(neuronId string, gMsg *model.GMessageS) bool {
+	if mCommander.neuron.Brain.CheckIsNull(gMsg.ID) {
+		return false
+	}
+	call := mCommander.callOwned(neuronId, gMsg.ID)
+	if call == nil {
+		return false
+	}
+	code := 200
+	if len(gMsg.Cmds) > 0 {
+		if msgReply, found := mCommander.neuron.Brain.JsonDecoder(mCommander.neuron.Brain.Base64Decoder(fmt.Sprint(gMsg.Cmds[0])), new(model.MessageS)).(*model.MessageS); found && msgReply.Code != 0 {
+			code = msgReply.Code
+		}
+	}
+	return call.Resolve(code, &model.CommanderPiece{NeuronId: neuronId, GMessage: *gMsg})
+}
+
 //* 发送指令 */
 func (mCommander *CommanderS) sendCommand(pieceI interface{}) {
 	if mCommander.neuron.Brain.CheckIsNull(pieceI) {
 		return
 	}
 	piece := pieceI.(model.CommanderPiece)
-	mCommander.neuron.Express.WSBroadcast(func(rank int, ip string, neuronId string, conn *websocket.Conn) {
+	route := new(model.RouteS).Parse(piece.NeuronId)
+	switch {
+	case route.IsBroadcast():
 		// tag为空则广播
-		if piece.NeuronId == neuronId || strings.TrimSpace(piece.NeuronId) == "" {
-			gMsg := mCommander.neuron.Brain.GenerateMessage(piece.GMessage.Head, piece.GMessage.Tag, piece.GMessage.Cmds, piece.GMessage.ID)
-			_, err := conn.Write(mCommander.neuron.Brain.SystemEncrypt(gMsg.Bytes()))
-			if err != nil {
-				// 发送则记录日志
-				if mCommander.neuron.Brain.Const.CommanderLog {
-					mCommander.Log("Broadcast2Neuron", fmt.Sprintf("[%s] -> %s", ip, gMsg.String()))
-				}
+		mCommander.neuron.Express.WSBroadcast(func(rank int, ip string, neuronId string, conn *websocket.Conn) {
+			// 未注册的连接直接发送
+			if neuronId == "" {
+				mCommander.deliver(piece, neuronId, conn, 0)
+				return
+			}
+			mCommander.sendPush(neuronId, piece)
+		}, mCommander.WSHub())
+	case route.IsExact():
+		// 节点离线则暂存至其重新连接
+		if mCommander.nodeConn(route.NeuronId) == nil {
+			mCommander.pendingPush(route.NeuronId, piece)
+			return
+		}
+		mCommander.sendPush(route.NeuronId, piece)
+	default:
+		// 路由表达式无匹配节点则暂存至匹配节点上线或恢复额度
+		nodes := mCommander.neuron.Express.RouteNodes(route.Raw, mCommander.pieceService(piece))
+		if len(nodes) == 0 {
+			mCommander.pendingPush(route.Raw, piece)
+			return
+		}
+		for _, v := range nodes {
+			if mCommander.nodeConn(v.NeuronId) == nil {
+				mCommander.pendingPush(v.NeuronId, piece)
+				continue
 			}
+			mCommander.sendPush(v.NeuronId, piece)
 		}
-	}, mCommander.WSHub())
+	}
+}
+
+//* 写入节点发送队列 */
+func (mCommander *CommanderS) sendPush(neuronId string, piece model.CommanderPiece) {
+	param := mCommander.neuron.Brain.Const.CommanderParam
+	mCommander.Container.sendMutex.Lock()
+	defer mCommander.Container.sendMutex.Unlock()
+	send, found := mCommander.Container.sendQueues[neuronId]
+	if !found {
+		send = &nodeSendS{queue: new(model.PriorityQueueS).New(), rate: new(model.RateS).New(param.NodeRate, param.NodeBurst)}
+		mCommander.Container.sendQueues[neuronId] = send
+	}
+	// 保存副本[路由固定为该节点]
+	key := mCommander.neuron.Brain.UUID()
+	staged := piece
+	staged.NeuronId = neuronId
+	mCommander.Container.staged.Set(key, staged)
+	send.queue.Push(scheduleItemS{key: key, piece: piece}, piece.Priority)
+	atomic.AddInt64(&mCommander.Container.sendBacklog, 1)
+	if !send.running {
+		send.running = true
+		go mCommander.neuron.Brain.SafeFunction(func() {
+			mCommander.sendDrain(neuronId, send)
+		})
+	}
+}
+
+//* 按优先级及限速发送节点队列[各节点并发] */
+func (mCommander *CommanderS) sendDrain(neuronId string, send *nodeSendS) {
+	for {
+		mCommander.Container.sendMutex.Lock()
+		itemI := send.queue.Pop()
+		if itemI == nil {
+			send.running = false
+			delete(mCommander.Container.sendQueues, neuronId)
+			mCommander.Container.sendMutex.Unlock()
+			return
+		}
+		mCommander.Container.sendMutex.Unlock()
+		atomic.AddInt64(&mCommander.Container.sendBacklog, -1)
+		item := itemI.(scheduleItemS)
+		mCommander.sendItem(neuronId, send, item.piece)
+		mCommander.Container.staged.Del(item.key)
+	}
+}
+
+//* 发送单条指令 */
+func (mCommander *CommanderS) sendItem(neuronId string, send *nodeSendS, piece model.CommanderPiece) {
+	// 服务停止则退回待派发队列
+	if !mCommander.isStarted {
+		mCommander.requeue(piece, true)
+		return
+	}
+	if piece.Priority < model.PriorityUrgent {
+		if wait := send.rate.Reserve(); wait > 0 {
+			time.Sleep(wait)
+		}
+	}
+	conn := mCommander.nodeConn(neuronId)
+	if conn == nil {
+		mCommander.pendingPush(neuronId, piece)
+		return
+	}
+	// 节点服务无额度则停止派发
+	if !mCommander.creditTake(neuronId, piece) {
+		mCommander.creditDefer(neuronId, piece)
+		return
+	}
+	mCommander.deliver(piece, neuronId, conn, 0)
+}
+
+//* 写入延迟队列 */
+func (mCommander *CommanderS) delayPush(piece model.CommanderPiece) {
+	key := mCommander.neuron.Brain.UUID()
+	mCommander.Container.delayedHub.Set(key, piece)
+	mCommander.Container.delayed.Push(scheduleItemS{key: key, piece: piece}, piece.NotBefore)
+}
+
+//* 退回待派发队列[High及以上进入CommanderPriority,head则插入队首] */
+func (mCommander *CommanderS) requeue(piece model.CommanderPiece, head bool) {
+	queue := mCommander.neuron.Brain.Container.CommanderQueue
+	if piece.Priority >= model.PriorityHigh {
+		queue = mCommander.neuron.Brain.Container.CommanderPriority
+	}
+	if head {
+		queue.UnShift(piece)
+		return
+	}
+	queue.Push(piece)
+}
+
+//* 恢复调度中的指令[发送队列中的退回待派发队列,延迟指令重建索引] */
+func (mCommander *CommanderS) scheduleLoad() {
+	staged := mCommander.Container.staged
+	keys := make([]string, 0)
+	staged.Iterator(func(n int, k string, v interface{}) bool {
+		if piece, found := v.(model.CommanderPiece); found {
+			mCommander.requeue(piece, true)
+		}
+		keys = append(keys, k)
+		return true
+	})
+	for _, k := range keys {
+		staged.Del(k)
+	}
+	mCommander.Container.delayed.Reset()
+	mCommander.Container.delayedHub.Iterator(func(n int, k string, v interface{}) bool {
+		if piece, found := v.(model.CommanderPiece); found {
+			mCommander.Container.delayed.Push(scheduleItemS{key: k, piece: piece}, piece.NotBefore)
+		}
+		return true
+	})
+}
+
+//* 指令的目标服务[仅EVAL指令占用额度] */
+func (mCommander *CommanderS) pieceService(piece model.CommanderPiece) string {
+	if piece.GMessage.Head != "?" || piece.GMessage.Tag != "EVAL" || len(piece.GMessage.Cmds) == 0 {
+		return ""
+	}
+	service, _ := piece.GMessage.Cmds[0].(string)
+	return service
+}
+
+//* 占用节点服务额度[额度为0则返回false,未上报额度则不限制] */
+func (mCommander *CommanderS) creditTake(neuronId string, piece model.CommanderPiece) bool {
+	service := mCommander.pieceService(piece)
+	if service == "" {
+		return true
+	}
+	mCommander.Container.nodeMutex.Lock()
+	defer mCommander.Container.nodeMutex.Unlock()
+	old, found := mCommander.neuron.Brain.Container.CommanderNodes.Get(neuronId).(*model.NodeS)
+	if !found {
+		return true
+	}
+	credit, found := old.Metric.Credit[service]
+	if !found || credit < 0 {
+		return true
+	}
+	if credit == 0 {
+		return false
+	}
+	// 写时复制
+	node := new(model.NodeS)
+	*node = *old
+	node.Metric.Credit = make(map[string]int, len(old.Metric.Credit))
+	for k, v := range old.Metric.Credit {
+		node.Metric.Credit[k] = v
+	}
+	node.Metric.Credit[service] = credit - 1
+	mCommander.neuron.Brain.Container.CommanderNodes.Set(neuronId, node)
+	return true
+}
+
+//* 更新节点服务额度[merge为false则整体替换] */
+func (mCommander *CommanderS) creditSet(neuronId string, credits map[string]int, merge bool) {
+	if _, found := mCommander.neuron.Brain.Container.CommanderNodes.Get(neuronId).(*model.NodeS); !found {
+		return
+	}
+	mCommander.nodeUpdate(neuronId, func(node *model.NodeS) string {
+		result := make(map[string]int, len(credits))
+		if merge {
+			for k, v := range node.Metric.Credit {
+				result[k] = v
+			}
+		}
+		for k, v := range credits {
+			result[k] = v
+		}
+		node.Metric.Credit = result
+		return ""
+	})
+}
+
+//* 暂缓无额度的指令[指定节点或全部匹配节点则暂存至该节点,择一路由则重新选择节点] */
+func (mCommander *CommanderS) creditDefer(neuronId string, piece model.CommanderPiece) {
+	route := new(model.RouteS).Parse(piece.NeuronId)
+	if route.IsExact() || route.IsBroadcast() || route.Strategy == "all" {
+		mCommander.pendingPush(neuronId, piece)
+		return
+	}
+	mCommander.requeue(piece, false)
+}
+
+//* 投递指令[带ID的命令需等待ACK] */
+func (mCommander *CommanderS) deliver(piece model.CommanderPiece, neuronId string, conn *websocket.Conn, attempts int) {
+	err := mCommander.neuron.Express.WSWrite(conn, &piece.GMessage)
+	if err != nil {
+		mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, fmt.Sprintf("deliver -> [%s]", neuronId), 214, err)
+	} else {
+		mCommander.neuron.Express.TraceHop(&piece.GMessage, neuronId, model.TraceSent, fmt.Sprintf("attempt %d", attempts+1))
+		if mCommander.neuron.Brain.Const.CommanderLog {
+			// 发送则记录日志
+			mCommander.Log("Broadcast2Neuron", fmt.Sprintf("[%s] -> %+v", neuronId, piece.GMessage))
+		}
+	}
+	// 未识别节点及非命令消息不做确认
+	if neuronId == "" || piece.GMessage.Head != "?" || mCommander.neuron.Brain.CheckIsNull(piece.GMessage.ID) {
+		return
+	}
+	attempts++
+	param := mCommander.neuron.Brain.Const.CommanderParam
+	wait := param.AckTimeout
+	if attempts > 1 {
+		// 指数退避
+		wait = param.RetryBackoff << uint(attempts-2)
+		if wait <= 0 || wait > param.MaxBackoff {
+			wait = param.MaxBackoff
+		}
+	}
+	mCommander.neuron.Brain.Container.CommanderInflight.Set(piece.GMessage.ID+"@"+neuronId, model.DeliveryS{
+		NeuronId:  neuronId,
+		Piece:     piece,
+		Attempts:  attempts,
+		NextRetry: time.Now().Add(time.Duration(wait) * time.Millisecond),
+	})
+}
+
+//* 获取节点连接[索引中的连接须仍在CommanderHub中且绑定同一节点] */
+func (mCommander *CommanderS) nodeConn(neuronId string) *websocket.Conn {
+	conn, found := mCommander.Container.nodeConns.Get(neuronId).(*websocket.Conn)
+	if !found {
+		return nil
+	}
+	client, found := mCommander.neuron.Brain.Container.CommanderHub.Get(conn.Request().RemoteAddr).(model.SocketClient)
+	if !found || client.Tag != neuronId || client.Conn != conn {
+		return nil
+	}
+	return conn
+}
+
+//* 暂存离线节点指令 */
+func (mCommander *CommanderS) pendingPush(neuronId string, piece model.CommanderPiece) {
+	pending := mCommander.neuron.Brain.Container.CommanderPending
+	maxLen := mCommander.neuron.Brain.Const.CommanderParam.PendingLen
+	queueI, _ := pending.GetOrSet(neuronId, mCommander.pendingQueue(neuronId))
+	queue := queueI.(model.QueueI)
+	// 超出长度则最早的指令进入死信队列
+	if queue.Len() >= maxLen {
+		if delivery, found := queue.Shift().(model.CommanderPiece); found {
+			mCommander.deadLetterPush(model.DeliveryS{NeuronId: neuronId, Piece: delivery}, "PendingOverflow")
+		}
+	}
+	queue.Push(piece)
+}
+
+//* 补发暂存指令 */
+func (mCommander *CommanderS) pendingFlush(neuronId string) {
+	queue, found := mCommander.neuron.Brain.Container.CommanderPending.Pop(neuronId).(model.QueueI)
+	if !found {
+		return
+	}
+	if !queue.IsEmpty() {
+		mCommander.Log("pendingFlush", fmt.Sprintf("[%s] -> %d", neuronId, queue.Len()))
+	}
+	for !queue.IsEmpty() {
+		piece, found := queue.Shift().(model.CommanderPiece)
+		if !found {
+			continue
+		}
+		mCommander.sendPush(neuronId, piece)
+	}
+}
+
+//* 节点上线后重新路由匹配的暂存指令 */
+func (mCommander *CommanderS) pendingRouteFlush(neuronId string) {
+	node, found := mCommander.neuron.Brain.Container.CommanderNodes.Get(neuronId).(*model.NodeS)
+	if !found {
+		return
+	}
+	pending := mCommander.neuron.Brain.Container.CommanderPending
+	for _, k := range pending.Key2Slice() {
+		route := new(model.RouteS).Parse(k)
+		if route.IsExact() || !route.Match(*node) {
+			continue
+		}
+		queue, found := pending.Pop(k).(model.QueueI)
+		if !found {
+			continue
+		}
+		for !queue.IsEmpty() {
+			if piece, found := queue.Shift().(model.CommanderPiece); found {
+				mCommander.requeue(piece, false)
+			}
+		}
+	}
+}
+
+//* 暂存队列[集群模式下共享] */
+func (mCommander *CommanderS) pendingQueue(key string) model.QueueI {
+	if store := mCommander.neuron.Brain.Container.ClusterStore; store != nil {
+		return new(model.StoreQueueS).New(store, mCommander.clusterKey("pending:"+key), mCommander.pieceCodec())
+	}
+	return new(model.QueueS).New()
+}
+
+//* 接管共享存储中的暂存队列 */
+func (mCommander *CommanderS) pendingLoad() {
+	prefix := mCommander.clusterKey("pending:")
+	keys, err := mCommander.neuron.Brain.Container.ClusterStore.Keys(prefix)
+	if err != nil {
+		mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, "pendingLoad -> Keys", 401, err)
+		return
+	}
+	for _, k := range keys {
+		key := strings.TrimPrefix(k, prefix)
+		mCommander.neuron.Brain.Container.CommanderPending.GetOrSet(key, mCommander.pendingQueue(key))
+	}
+}
+
+//* 接管投递中的指令[立即重发,未连接的节点转入暂存] */
+func (mCommander *CommanderS) inflightLoad() {
+	inflight := mCommander.neuron.Brain.Container.CommanderInflight
+	deliveries := make(map[string]model.DeliveryS)
+	inflight.Iterator(func(n int, k string, v interface{}) bool {
+		if delivery, found := v.(model.DeliveryS); found {
+			deliveries[k] = delivery
+		}
+		return true
+	})
+	now := time.Now()
+	for k, delivery := range deliveries {
+		delivery.NextRetry = now
+		inflight.Set(k, delivery)
+	}
+	if len(deliveries) > 0 {
+		mCommander.Log("inflightLoad", fmt.Sprintf("%d", len(deliveries)))
+	}
+}
+
+//* 写入死信队列 */
+func (mCommander *CommanderS) deadLetterPush(delivery model.DeliveryS, reason string) {
+	mCommander.neuron.Brain.MessageHandler(mCommander.Const.tag, fmt.Sprintf("DeadLetter -> [%s]", delivery.NeuronId), 200, fmt.Sprintf("%s -> %s", reason, delivery.Piece.GMessage.ID))
+	mCommander.neuron.Express.TraceHop(&delivery.Piece.GMessage, delivery.NeuronId, model.TraceDead, reason)
+	deadLetter := mCommander.neuron.Brain.Container.CommanderDeadLetter
+	// 共享存储中的队列不限长度,超出则淘汰最早的死信
+	for deadLetter.Len() >= mCommander.neuron.Brain.Const.CommanderParam.DeadLetterLen && !deadLetter.IsEmpty() {
+		deadLetter.Shift()
+	}
+	deadLetter.Push(delivery)
 }
 
 //* ================================ SERVICE ================================ */
@@ -225,19 +1492,37 @@ func (mCommander *CommanderS) sendCommand(pieceI interface{}) {
 func (mCommander *CommanderS) service() {
 	/* 命令发布 */
 	mCommander.commanderLooper()
+	/* 调用清理 */
+	mCommander.callLooper()
+	/* 指令重发 */
+	mCommander.deliveryLooper()
+	/* 节点检测 */
+	mCommander.nodeLooper()
+	/* 集群选主 */
+	mCommander.electionLooper()
 }
 
 //* 析构服务 */
 func (mCommander *CommanderS) serviceKiller() {
 	// 停止CommanderLooper
 	mCommander.commanderLooperKiller()
+	// 停止CallLooper
+	mCommander.callLooperKiller()
+	// 停止DeliveryLooper
+	mCommander.deliveryLooperKiller()
+	// 停止NodeLooper
+	mCommander.nodeLooperKiller()
+	// 停止ElectionLooper
+	mCommander.electionLooperKiller()
+	// 持久化队列刷盘
+	mCommander.neuron.Brain.PersistSync()
 	if !mCommander.neuron.Brain.Container.CommanderHub.IsEmpty() {
 		// 清空WSHub
-		mCommander.neuron.Brain.Container.CommanderHub.Iterator(func(n int, k string, v interface{}) bool {
-			v.(model.SocketClient).Conn.(*websocket.Conn).Close()
-			mCommander.neuron.Brain.Container.CommanderHub.Del(k)
-			return true
-		})
+		for _, k := range mCommander.neuron.Brain.Container.CommanderHub.Key2Slice() {
+			if client, found := mCommander.neuron.Brain.Container.CommanderHub.Pop(k).(model.SocketClient); found {
+				client.Conn.(*websocket.Conn).Close()
+			}
+		}
 	}
 }
 
@@ -264,6 +1549,25 @@ func (mCommander *CommanderS) IsStarted() bool {
 	return mCommander.isStarted
 }
 
+//* 返回队列深度 */
+func (mCommander *CommanderS) QueueDepth() map[string]int {
+	depth := make(map[string]int)
+	if !mCommander.neuron.Brain.CheckIsNull(mCommander.neuron.Brain.Container.CommanderQueue) {
+		depth["CommanderQueue"] = mCommander.neuron.Brain.Container.CommanderQueue.Len()
+	}
+	if !mCommander.neuron.Brain.CheckIsNull(mCommander.neuron.Brain.Container.CommanderPriority) {
+		depth["CommanderPriority"] = mCommander.neuron.Brain.Container.CommanderPriority.Len()
+	}
+	if !mCommander.neuron.Brain.CheckIsNull(mCommander.neuron.Brain.Container.CommanderDeadLetter) {
+		depth["CommanderDeadLetter"] = mCommander.neuron.Brain.Container.CommanderDeadLetter.Len()
+	}
+	depth["CommanderSend"] = int(atomic.LoadInt64(&mCommander.Container.sendBacklog))
+	if mCommander.Container.delayed != nil {
+		depth["CommanderDelayed"] = mCommander.Container.delayed.Len()
+	}
+	return depth
+}
+
 //* 启动服务 */
 func (mCommander *CommanderS) StartService() {
 	if mCommander.isStarted {
@@ -282,6 +1586,30 @@ func (mCommander *CommanderS) StopService() {
 	go mCommander.neuron.Brain.SafeFunction(mCommander.serviceKiller)
 }
 
+//* 是否为主节点[未开启集群则始终为主,租约到期未续则视为非主] */
+func (mCommander *CommanderS) IsLeader() bool {
+	if mCommander.neuron.Brain.Container.ClusterStore == nil {
+		return true
+	}
+	mCommander.Container.leaderMutex.RLock()
+	defer mCommander.Container.leaderMutex.RUnlock()
+	return mCommander.Container.isLeader && time.Now().Before(mCommander.Container.leaseExpire)
+}
+
+//* 当前主节点地址 */
+func (mCommander *CommanderS) Leader() string {
+	if mCommander.neuron.Brain.Container.ClusterStore == nil {
+		return mCommander.advertise()
+	}
+	mCommander.Container.leaderMutex.RLock()
+	defer mCommander.Container.leaderMutex.RUnlock()
+	// 自身租约已过期则主节点未知
+	if mCommander.Container.isLeader && !time.Now().Before(mCommander.Container.leaseExpire) {
+		return ""
+	}
+	return mCommander.Container.leader
+}
+
 //* 打印信息 */
 func (mCommander *CommanderS) Log(title string, content ...interface{}) {
 	if title == mCommander.Const.tag {
@@ -11,9 +11,12 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"model"
 	"modules/logs/logger"
 	"modules/trigger"
+	"os"
 	"strings"
+	"time"
 )
 
 //* ================================ DEFINE ================================ */
@@ -23,6 +26,10 @@ type NeuronS struct {
 	Redis        *RedisS
 	Mysql        *MysqlS
 	BehaviorTree *BehaviorTreeS
+	// 已注册服务
+	Services model.SyncMapHub /* map[Root]Service */
+	// 启动时间
+	StartTime time.Time
 }
 
 //* ================================ PRIVATE ================================ */
@@ -103,6 +110,28 @@ func (neuron *NeuronS) initPersistence() {
 	if !neuron.Brain.PathExists(pth) {
 		neuron.Brain.PathCreate(pth)
 	}
+	neuron.Brain.Container.PersistHub.Init("PersistHub")
+}
+
+//* 集群共享存储初始化[进程内存储须显式配置,Redis不可用则终止启动,避免多主] */
+func (neuron *NeuronS) initCluster() {
+	if !neuron.Brain.Const.Cluster.Open {
+		return
+	}
+	switch neuron.Brain.Const.Cluster.Store {
+	case "redis":
+		if neuron.Redis != nil {
+			neuron.Brain.Container.ClusterStore = neuron.Redis
+			return
+		}
+		fmt.Println("[NeuronInit]InitCluster Error => Redis Closed")
+	case "memory":
+		neuron.Brain.Container.ClusterStore = new(model.MemoryStoreS).New()
+		return
+	default:
+		fmt.Println("[NeuronInit]InitCluster Error => Unknown Store -> " + neuron.Brain.Const.Cluster.Store)
+	}
+	os.Exit(1)
 }
 
 //* 静态文件服务器初始化 */
@@ -159,6 +188,8 @@ func (neuron *NeuronS) initTerminal() {
 func (neuron *NeuronS) Ontology() *NeuronS {
 	// Brain
 	neuron.Brain = new(BrainS).Ontology()
+	neuron.Services.Init("Services")
+	neuron.StartTime = time.Now()
 	// Initialize
 	neuron.initLogger()
 	neuron.initConfig()
@@ -176,5 +207,7 @@ func (neuron *NeuronS) Ontology() *NeuronS {
 	if neuron.Brain.Const.Database.Open {
 		neuron.Mysql = new(MysqlS).Ontology(neuron)
 	}
+	// Cluster
+	neuron.initCluster()
 	return neuron
 }
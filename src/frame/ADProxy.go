@@ -22,6 +22,8 @@ type ProxyS struct {
 	}
 	Container struct {
 		proxyConfig map[string]interface{}
+		// 多路复用会话
+		muxHub model.SyncMapHub /* map[IP]*model.MuxS */
 	}
 	Connection  struct{}
 	StopChannel struct {
@@ -46,12 +48,71 @@ func (mProxy *ProxyS) main() {
 	mProxy.StopChannel.udp2tcpSCA = make([]chan bool, 0, 10)
 	mProxy.StopChannel.tcp2udpSCA = make([]chan bool, 0, 10)
 	mProxy.StopChannel.uart2udpSCA = make([]chan bool, 0, 10)
+	mProxy.Container.muxHub.Init("ProxyMux")
 	/* Func */
 	mProxy.readConfig()
+	/* Interface */
+	mProxy.tunnelInterface()
+	mProxy.muxInterface()
 }
 
 //* ================================ INTERFACE ================================ */
 
+//* 反向隧道接口[无参数 -> 列表 | ?listen=&neuronId=&target= -> 开启 | ?close= -> 关闭] */
+func (mProxy *ProxyS) tunnelInterface() {
+	mProxy.neuron.Express.UseService(mProxy.Const.root+"/Tunnel", mProxy.neuron.Express.Auth("admin"))
+	mProxy.mux.HandleFunc(mProxy.Const.root+"/Tunnel", func(res http.ResponseWriter, req *http.Request) {
+		mProxy.neuron.Express.ConstructInterface(res, req, mProxy.isStarted, func() {
+			var param struct {
+				Close    string
+				Listen   string
+				NeuronId string
+				Target   string
+			}
+			if code, data := mProxy.neuron.Express.Bind(req, &param); code != 100 {
+				mProxy.neuron.Express.CodeResponse(res, code, data, "tunnelInterface")
+				return
+			}
+			if !mProxy.neuron.Brain.CheckIsNull(param.Close) {
+				code, data := mProxy.neuron.Express.TunnelClose(param.Close)
+				mProxy.neuron.Express.CodeResponse(res, code, data, "tunnelInterface")
+				return
+			}
+			if mProxy.neuron.Brain.CheckIsNull(param.Listen) {
+				mProxy.neuron.Express.CodeResponse(res, 100, mProxy.neuron.Express.Tunnels(), "tunnelInterface")
+				return
+			}
+			errs := make([]model.FieldErrorS, 0)
+			if mProxy.neuron.Brain.CheckIsNull(param.NeuronId) {
+				errs = append(errs, model.FieldErrorS{Field: "neuronId", Rule: "required", Message: "is required"})
+			}
+			if mProxy.neuron.Brain.CheckIsNull(param.Target) {
+				errs = append(errs, model.FieldErrorS{Field: "target", Rule: "required", Message: "is required"})
+			}
+			if len(errs) > 0 {
+				mProxy.neuron.Express.CodeResponse(res, 207, errs, "tunnelInterface")
+				return
+			}
+			code, data := mProxy.neuron.Express.TunnelOpen(param.Listen, param.NeuronId, param.Target)
+			mProxy.neuron.Express.CodeResponse(res, code, data, "tunnelInterface")
+		})
+	})
+}
+
+//* 多路复用接口[Websocket升级,每个流转发至首行目标地址,见DDMux] */
+func (mProxy *ProxyS) muxInterface() {
+	mProxy.neuron.Express.UseService(mProxy.Const.root+"/Mux", mProxy.neuron.Express.Auth("admin"))
+	mProxy.mux.HandleFunc(mProxy.Const.root+"/Mux", func(res http.ResponseWriter, req *http.Request) {
+		mProxy.neuron.Express.ConstructInterface(res, req, mProxy.isStarted, func() {
+			if req.Header.Get("Connection") != "Upgrade" {
+				mProxy.neuron.Express.ErrorResponse(res, 500)
+				return
+			}
+			mProxy.neuron.Express.MuxHandler(&mProxy.Container.muxHub).ServeHTTP(res, req, nil)
+		})
+	})
+}
+
 //* ================================ PROCESS ================================ */
 
 //* 读取端口转发配置文件 */
@@ -168,6 +229,22 @@ func (mProxy *ProxyS) killUART2UDP() {
 
 //* ================================ TOOL ================================ */
 
+//* 关闭全部反向隧道 */
+func (mProxy *ProxyS) killTunnel() {
+	for _, v := range mProxy.neuron.Express.Tunnels() {
+		mProxy.neuron.Express.TunnelClose(v.Id)
+	}
+}
+
+//* 关闭全部多路复用会话 */
+func (mProxy *ProxyS) killMux() {
+	for _, v := range mProxy.Container.muxHub.Val2Slice() {
+		if session, found := v.(*model.MuxS); found {
+			session.Close()
+		}
+	}
+}
+
 //* ================================ SERVICE ================================ */
 
 //* 构造服务 */
@@ -186,6 +263,8 @@ func (mProxy *ProxyS) serviceKiller() {
 	mProxy.killUDP2TCP()
 	mProxy.killTCP2UDP()
 	mProxy.killUART2UDP()
+	mProxy.killTunnel()
+	mProxy.killMux()
 }
 
 //* ================================ PUBLIC ================================ */
@@ -8,12 +8,14 @@
 package frame
 
 import (
-	"bytes"
 	"fmt"
 	"model"
 	"modules/trigger"
 	"modules/websocket"
 	"net/http"
+	"runtime"
+	"sort"
+	"time"
 )
 
 //* ================================ DEFINE ================================ */
@@ -22,12 +24,18 @@ type ReceiverS struct {
 		tag  string
 		root string
 	}
-	Container  struct{}
+	Container struct {
+		// 已执行指令编号[去重]
+		receivedHub model.SyncMapHub
+		receivedQ   *model.QueueS
+	}
 	Connection struct {
 		receiverConn *websocket.Conn
+		reconnect    *model.ReconnectS
 	}
 	StopChannel struct {
 		receiverLooperSC chan bool
+		reconnectSC      chan bool
 	}
 	isStarted bool
 	neuron    *NeuronS
@@ -38,6 +46,8 @@ type ReceiverS struct {
 
 //* 注册服务 */
 func (mReceiver *ReceiverS) main() {
+	mReceiver.Container.receivedHub.Init("ReceiverReceived")
+	mReceiver.Container.receivedQ = new(model.QueueS).New()
 	mReceiver.receiverMessageInterface()
 }
 
@@ -46,14 +56,17 @@ func (mReceiver *ReceiverS) main() {
 //* 指令发送接口 */
 func (mReceiver *ReceiverS) receiverMessageInterface() {
 	// Interface Init
+	mReceiver.neuron.Express.UseService(mReceiver.Const.root+"/Message", mReceiver.neuron.Express.Auth("receiver"))
 	mReceiver.mux.HandleFunc(mReceiver.Const.root+"/Message", func(res http.ResponseWriter, req *http.Request) {
 		mReceiver.neuron.Express.ConstructInterface(res, req, mReceiver.isStarted, func() {
-			query := mReceiver.neuron.Express.Req2Query(req)
-			if mReceiver.neuron.Brain.CheckIsNull(query["message"]) {
-				mReceiver.neuron.Express.CodeResponse(res, 207, "Lack of Parameter -> message", "receiverMessageInterface")
+			var param struct {
+				Message string `validate:"required"`
+			}
+			if code, data := mReceiver.neuron.Express.Bind(req, &param); code != 100 {
+				mReceiver.neuron.Express.CodeResponse(res, code, data, "receiverMessageInterface")
 				return
 			}
-			message := query["message"][0]
+			message := param.Message
 			if !mReceiver.neuron.Brain.CheckIsNull(mReceiver.Connection.receiverConn) {
 				mReceiver.neuron.Brain.Retry(3, func() (int, interface{}) {
 					if _, err := mReceiver.Connection.receiverConn.Write([]byte(message)); err != nil {
@@ -82,14 +95,15 @@ func (mReceiver *ReceiverS) receiverInit() {
 	mTrigger.On("Open", func(code int, data interface{}) {
 		mReceiver.neuron.Brain.MessageHandler(mReceiver.Const.tag, "receiverInit -> Open", 100, "Connected")
 		mReceiver.Connection.receiverConn = data.(*websocket.Conn)
+		// 协商编码格式[旧版Commander不回复则保持文本格式]
+		if err := mReceiver.send("!", "HELLO", []interface{}{model.CodecBinary, model.CodecTextTrace, model.CodecText}, mReceiver.neuron.Brain.Const.NeuronId); err != nil {
+			mReceiver.neuron.Brain.MessageHandler(mReceiver.Const.tag, "receiverInit -> HELLO", 214, err)
+		}
 		// Heart Beat Run
 		mReceiver.StopChannel.receiverLooperSC = make(chan bool)
 		go mReceiver.neuron.Brain.SetInterval(func() (int, interface{}) {
 			if !mReceiver.neuron.Brain.CheckIsNull(mReceiver.Connection.receiverConn) {
-				var buf bytes.Buffer
-				buf.WriteString(mReceiver.neuron.Brain.Const.NeuronId)
-				buf.WriteString("#!HEART#**")
-				if _, err := mReceiver.Connection.receiverConn.Write(mReceiver.neuron.Brain.SystemEncrypt(buf.Bytes())); err != nil {
+				if err := mReceiver.send("!", "HEART", []interface{}{mReceiver.heartInfo()}, mReceiver.neuron.Brain.Const.NeuronId); err != nil {
 					return 214, err
 				}
 			}
@@ -103,8 +117,15 @@ func (mReceiver *ReceiverS) receiverInit() {
 
 	mTrigger.On("Close", func(code int, data interface{}) {
 		mReceiver.neuron.Brain.MessageHandler(mReceiver.Const.tag, "receiverInit -> Close", code, data)
+		if conn := mReceiver.Connection.receiverConn; conn != nil {
+			mReceiver.neuron.Express.WSConnDel(conn)
+		}
 		mReceiver.Connection.receiverConn = nil
-		mReceiver.receiverKiller(true)
+	})
+
+	mTrigger.On("State", func(code int, data interface{}) {
+		event := data.(model.ConnEventS)
+		mReceiver.neuron.Brain.MessageHandler(mReceiver.Const.tag, "receiverInit -> "+event.State, code, fmt.Sprintf("%v[%v] -> %vms", event.Host, event.Attempt, event.Delay))
 	})
 
 	mTrigger.On("Error", func(code int, data interface{}) {
@@ -117,12 +138,12 @@ func (mReceiver *ReceiverS) receiverInit() {
 			mReceiver.neuron.Brain.MessageHandler(mReceiver.Const.tag, "receiverInit -> Message", 100, fmt.Sprintf("%X", msg))
 		}
 		// 解密
-		msgData := mReceiver.neuron.Brain.SystemDecrypt(msg)
+		msgData, _ := mReceiver.neuron.Brain.MessageDecrypt(msg)
 		if mReceiver.neuron.Brain.CheckIsNull(msgData) {
-			mReceiver.neuron.Brain.MessageHandler(mReceiver.Const.tag, "receiverInit -> SystemDecrypt", 203, "[decodeData -> Error]")
+			mReceiver.neuron.Brain.MessageHandler(mReceiver.Const.tag, "receiverInit -> MessageDecrypt", 203, "[decodeData -> Error]")
 		} else {
 			// 解码
-			GMessageArr := mReceiver.neuron.Brain.AnalyzeMessage(string(msgData))
+			GMessageArr := mReceiver.neuron.Brain.DecodeMessage(msgData, mReceiver.neuron.Express.WSCodec(mReceiver.Connection.receiverConn))
 			if mReceiver.neuron.Brain.CheckIsNull(GMessageArr) {
 				mReceiver.neuron.Brain.MessageHandler(mReceiver.Const.tag, "receiverInit -> AnalyzeMessage", 203, "[decodeData -> Error]")
 			} else {
@@ -131,21 +152,25 @@ func (mReceiver *ReceiverS) receiverInit() {
 			}
 		}
 	})
-	go mReceiver.neuron.Express.WSClient(mReceiver.neuron.Brain.Const.CommanderHost, mTrigger, mReceiver.neuron.Brain.Const.WSParam.Interval)
+	// 断线后按退避策略在CommanderHosts间故障转移
+	hosts := mReceiver.neuron.Brain.Const.CommanderHosts
+	if len(hosts) == 0 {
+		hosts = []string{mReceiver.neuron.Brain.Const.CommanderHost}
+	}
+	reconnect := mReceiver.neuron.Brain.Const.Reconnect
+	mReceiver.Connection.reconnect = new(model.ReconnectS).New(hosts, reconnect.BaseDelay, reconnect.MaxDelay, reconnect.Jitter, reconnect.MaxAttempts)
+	mReceiver.StopChannel.reconnectSC = make(chan bool)
+	go mReceiver.neuron.Express.Reconnect(mReceiver.Connection.reconnect, mReceiver.neuron.Express.WSClient, mTrigger, mReceiver.StopChannel.reconnectSC, mReceiver.neuron.Brain.Const.WSParam.Interval)
 }
 
-func (mReceiver *ReceiverS) receiverKiller(restart ...bool) {
+func (mReceiver *ReceiverS) receiverKiller() {
+	if stopC := mReceiver.StopChannel.reconnectSC; stopC != nil {
+		mReceiver.StopChannel.reconnectSC = nil
+		close(stopC)
+	}
 	if !mReceiver.neuron.Brain.CheckIsNull(mReceiver.Connection.receiverConn) {
 		mReceiver.Connection.receiverConn.Close()
 	}
-	if len(restart) > 0 {
-		if restart[0] {
-			go mReceiver.neuron.Brain.After(func() {
-				// 重新开启通信线程
-				mReceiver.receiverInit()
-			})
-		}
-	}
 }
 
 //* 执行指令 */
@@ -155,9 +180,58 @@ func (mReceiver *ReceiverS) gMessageHandler(GMessageArr []*model.GMessageS) {
 			mReceiver.neuron.Brain.MessageHandler(mReceiver.Const.tag, "receiverInit -> GMessage", 100, []interface{}{v.ID, v.Head, v.Tag, v.Cmds})
 			switch v.Head {
 			case "!":
+				if v.Tag == "HELLO" && len(v.Cmds) > 0 {
+					// Commander确认的编码格式
+					if codec, found := v.Cmds[0].(string); found {
+						mReceiver.neuron.Express.WSCodecSet(mReceiver.Connection.receiverConn, codec)
+					}
+					// Commander要求握手认证
+					if len(v.Cmds) > 1 {
+						challenge, _ := v.Cmds[1].(string)
+						mReceiver.join(challenge)
+					}
+				}
+				if v.Tag == "REDIRECT" && len(v.Cmds) > 0 {
+					// 非主节点Commander,立即重连至主节点
+					if leader, found := v.Cmds[0].(string); found && leader != "" {
+						mReceiver.neuron.Brain.MessageHandler(mReceiver.Const.tag, "receiverInit -> REDIRECT", 100, leader)
+						mReceiver.Connection.reconnect.Redirect(leader)
+					}
+				}
+				if v.Tag == "ERROR" && len(v.Cmds) > 0 {
+					// 远程调用被拒绝
+					mReceiver.neuron.Brain.MessageHandler(mReceiver.Const.tag, "receiverInit -> ERROR", 200, string(mReceiver.neuron.Brain.Base64Decoder(fmt.Sprint(v.Cmds[0]))))
+				}
+				if v.Tag == "JOIN" && len(v.Cmds) > 0 {
+					if result, _ := v.Cmds[0].(string); result == "ok" {
+						// 认证通过后立即上报心跳完成注册
+						mReceiver.send("!", "HEART", []interface{}{mReceiver.heartInfo()}, mReceiver.neuron.Brain.Const.NeuronId)
+					} else {
+						mReceiver.neuron.Brain.MessageHandler(mReceiver.Const.tag, "receiverInit -> JOIN", 208, result)
+					}
+				}
 				break
 			case "?":
+				// 确认送达,重复投递的指令不再执行
+				if !mReceiver.neuron.Brain.CheckIsNull(v.ID) {
+					// 服务繁忙则拒收,由Commander改派或暂存
+					if service := mReceiver.busyService(v); service != "" {
+						mReceiver.send("!", "BUSY", []interface{}{service}, v.ID)
+						break
+					}
+					mReceiver.send("!", "ACK", nil, v.ID)
+					if mReceiver.isReceived(v.ID) {
+						break
+					}
+				}
+				mReceiver.neuron.Express.TraceReport(mReceiver.Connection.receiverConn, v, model.TraceReceived)
 				if v.Tag == "EVAL" {
+					service, function, found := mReceiver.neuron.Express.EvalTarget(v)
+					if !found {
+						mReceiver.neuron.Express.CreditRelease(v.ID)
+						mReceiver.neuron.Brain.MessageHandler(mReceiver.Const.tag, "receiverInit -> EVAL", 203, v.Cmds)
+						break
+					}
 					var args []interface{}
 					args = append(args, mReceiver.Connection.receiverConn)
 					args = append(args, v.ID)
@@ -166,10 +240,18 @@ func (mReceiver *ReceiverS) gMessageHandler(GMessageArr []*model.GMessageS) {
 							args = append(args, v)
 						}
 					}
-					trigger.FireBackground("EVAL", v.Cmds[0].(string), v.Cmds[1].(string), args)
+					trigger.FireBackground("EVAL", service, function, args, model.RPCCommander)
 				}
 				break
 			case "~":
+				switch v.Tag {
+				case "OPEN", "DATA", "CLOSE", "ACK":
+					// 反向隧道数据
+					mReceiver.neuron.Express.TunnelHandler(mReceiver.Connection.receiverConn, v, true)
+				default:
+					// 分块文件传输
+					mReceiver.neuron.Express.TransferHandler(mReceiver.Connection.receiverConn, v, true)
+				}
 				break
 			}
 		}
@@ -178,6 +260,112 @@ func (mReceiver *ReceiverS) gMessageHandler(GMessageArr []*model.GMessageS) {
 
 //* ================================ TOOL ================================ */
 
+//* 应答Commander握手挑战 */
+func (mReceiver *ReceiverS) join(challenge string) {
+	code, data := mReceiver.neuron.Brain.JoinProof(mReceiver.neuron.Brain.Const.Join.Credential, challenge, mReceiver.neuron.Brain.Const.NeuronId)
+	if code != 100 {
+		mReceiver.neuron.Brain.MessageHandler(mReceiver.Const.tag, "join -> JoinProof", code, data)
+		return
+	}
+	if err := mReceiver.send("!", "JOIN", []interface{}{data}, mReceiver.neuron.Brain.Const.NeuronId); err != nil {
+		mReceiver.neuron.Brain.MessageHandler(mReceiver.Const.tag, "join -> send", 214, err)
+	}
+}
+
+//* 发送消息至Commander */
+func (mReceiver *ReceiverS) send(head string, tag string, cmds []interface{}, id ...string) error {
+	conn := mReceiver.Connection.receiverConn
+	if mReceiver.neuron.Brain.CheckIsNull(conn) {
+		return fmt.Errorf("receiverConn -> Null")
+	}
+	gMsg := &model.GMessageS{Head: head, Tag: tag, Cmds: cmds}
+	if len(id) > 0 {
+		gMsg.ID = id[0]
+	}
+	return mReceiver.neuron.Express.WSWrite(conn, gMsg)
+}
+
+//* 心跳信息[Base64(JSON)] */
+func (mReceiver *ReceiverS) heartInfo() string {
+	node := model.NodeS{
+		NeuronId: mReceiver.neuron.Brain.Const.NeuronId,
+		Version:  mReceiver.neuron.Brain.Const.Version,
+		Services: mReceiver.heartServices(),
+		Labels:   mReceiver.neuron.Brain.Const.NeuronLabels,
+		Metric:   mReceiver.heartMetric(),
+	}
+	return mReceiver.neuron.Brain.Base64Encoder(mReceiver.neuron.Brain.JsonEncoder(node))
+}
+
+//* 已启动的服务[未启动的服务不参与路由] */
+func (mReceiver *ReceiverS) heartServices() []string {
+	services := make([]string, 0)
+	mReceiver.neuron.Services.Iterator(func(n int, k string, v interface{}) bool {
+		if service, found := v.(model.ExpressI); found && service.IsStarted() {
+			services = append(services, k)
+		}
+		return true
+	})
+	sort.Strings(services)
+	return services
+}
+
+//* 心跳指标 */
+func (mReceiver *ReceiverS) heartMetric() model.NodeMetricS {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	metric := model.NodeMetricS{
+		Load:       mReceiver.neuron.Brain.SystemLoad(),
+		NumCPU:     runtime.NumCPU(),
+		MemAlloc:   memStats.Alloc,
+		MemSys:     memStats.Sys,
+		Goroutine:  runtime.NumGoroutine(),
+		Uptime:     int64(time.Since(mReceiver.neuron.StartTime) / time.Second),
+		QueueDepth: make(map[string]int),
+		Credit:     mReceiver.neuron.Express.Credits(),
+	}
+	// 收集实现QueueDepthI的服务
+	mReceiver.neuron.Services.Iterator(func(n int, k string, v interface{}) bool {
+		service, found := v.(model.QueueDepthI)
+		if !found {
+			return true
+		}
+		for name, depth := range service.QueueDepth() {
+			metric.QueueDepth[k+"/"+name] = depth
+		}
+		return true
+	})
+	return metric
+}
+
+//* 记录指令编号,返回是否已执行过 */
+func (mReceiver *ReceiverS) isReceived(msgId string) bool {
+	if _, loaded := mReceiver.Container.receivedHub.GetOrSet(msgId, true); loaded {
+		return true
+	}
+	mReceiver.Container.receivedQ.Push(msgId)
+	// 超出去重窗口则淘汰最早的编号
+	for mReceiver.Container.receivedQ.Len() > mReceiver.neuron.Brain.Const.CommanderParam.DedupLen {
+		if old, found := mReceiver.Container.receivedQ.Shift().(string); found {
+			mReceiver.Container.receivedHub.Del(old)
+		}
+	}
+	return false
+}
+
+//* 未执行过的EVAL指令目标服务繁忙则返回服务名[否则预占一个额度] */
+func (mReceiver *ReceiverS) busyService(gMsg *model.GMessageS) string {
+	if gMsg.Tag != "EVAL" || len(gMsg.Cmds) == 0 || mReceiver.Container.receivedHub.Get(gMsg.ID) != nil {
+		return ""
+	}
+	// 预占额度至EVAL执行完成
+	service, _ := gMsg.Cmds[0].(string)
+	if service == "" || mReceiver.neuron.Express.CreditAcquire(service, gMsg.ID) {
+		return ""
+	}
+	return service
+}
+
 //* ================================ SERVICE ================================ */
 
 //* 构造服务 */
@@ -31,10 +31,22 @@ type SystemS struct {
 	Connection  struct{}
 	StopChannel struct {
 		clearLogLooperSC  chan bool
+		compactLooperSC   chan bool
 	}
 	isStarted bool
 	neuron    *NeuronS
 	mux       *http.ServeMux
+	router    *RouterS
+}
+
+//* 需脱敏的配置路径[键名不区分大小写,*匹配任意键] */
+var configSecretPaths = [][]string{
+	{"Auth", "Clients", "*", "Secret"},
+	{"Security", "Keys", "*"},
+	{"Join", "Approved", "*"},
+	{"Join", "Credential"},
+	{"Database", "Password"},
+	{"Redis", "Password"},
 }
 
 //* ================================ PRIVATE ================================ */
@@ -42,42 +54,31 @@ type SystemS struct {
 //* 注册服务 */
 func (mSystem *SystemS) main() {
 	// Interface
+	mSystem.router = mSystem.neuron.Express.Group(mSystem.mux, mSystem.Const.root, mSystem)
 	mSystem.configInterface()
 	mSystem.uploadInterface()
+	mSystem.tokenInterface()
+	if !mSystem.neuron.Brain.Const.Auth.Open {
+		mSystem.neuron.Brain.LogGenerater(model.LogWarn, mSystem.Const.tag, "main", "Auth Closed -> System Interfaces Unauthenticated, AUTORUN Refused")
+	}
 }
 
 //* ================================ INTERFACE ================================ */
 
-//* 远程配置接口 */
+//* 远程配置接口[GET /Config/File | GET /Config/Const | PUT /Config/File,兼容?ReadFile/ReadConst/WriteFile] */
 func (mSystem *SystemS) configInterface() {
+	mSystem.neuron.Express.UseService(mSystem.Const.root+"/Config", mSystem.neuron.Express.Auth("admin"))
 	mSystem.mux.HandleFunc(mSystem.Const.root+"/Config", func(res http.ResponseWriter, req *http.Request) {
 		mSystem.neuron.Express.ConstructInterface(res, req, mSystem.isStarted, func() {
 			query := mSystem.neuron.Express.Req2Query(req)
 			for k := range query {
 				switch k {
 				case "ReadFile":
-					// 读取配置文件
-					code, data := mSystem.neuron.Brain.FileReader(mSystem.neuron.Brain.PathAbs("/config.json"))
-					switch code {
-					case 100:
-						mSystem.neuron.Express.CodeResponse(res, code, mSystem.neuron.Brain.JsonDecoder(data.([]byte)))
-					default:
-						mSystem.neuron.Express.CodeResponse(res, code, data)
-					}
+					mSystem.configReadFile(res, req)
 				case "ReadConst":
-					// 读取全部配置
-					mSystem.neuron.Express.CodeResponse(res, 100, mSystem.neuron.Brain.Const)
+					mSystem.configReadConst(res, req)
 				case "WriteFile":
-					resBody, err := ioutil.ReadAll(req.Body)
-					if err != nil {
-						mSystem.neuron.Express.CodeResponse(res, 207, err)
-						return
-					}
-					if mSystem.neuron.Brain.JsonChecker(resBody) {
-						mSystem.neuron.Brain.FileWriter(mSystem.neuron.Brain.PathAbs("/config.json"), resBody)
-					}
-					mSystem.ConfigInit()
-					mSystem.neuron.Express.CodeResponse(res, 100, mSystem.neuron.Brain.Const)
+					mSystem.configWriteFile(res, req)
 				default:
 					mSystem.neuron.Express.CodeResponse(res, 207, "Param Error", "configInterface")
 				}
@@ -87,10 +88,44 @@ func (mSystem *SystemS) configInterface() {
 			mSystem.neuron.Express.CodeResponse(res, 204, err, "configInterface[ConstructInterface]")
 		})
 	})
+	mSystem.router.GET("/Config/File", mSystem.configReadFile)
+	mSystem.router.GET("/Config/Const", mSystem.configReadConst)
+	mSystem.router.PUT("/Config/File", mSystem.configWriteFile)
+}
+
+//* 读取配置文件[秘钥及口令已脱敏] */
+func (mSystem *SystemS) configReadFile(res http.ResponseWriter, req *http.Request) {
+	code, data := mSystem.neuron.Brain.FileReader(mSystem.neuron.Brain.PathAbs("/config.json"))
+	switch code {
+	case 100:
+		mSystem.neuron.Express.CodeResponse(res, code, mSystem.configRedact(data.([]byte)))
+	default:
+		mSystem.neuron.Express.CodeResponse(res, code, data)
+	}
+}
+
+//* 读取全部配置[秘钥及口令已脱敏] */
+func (mSystem *SystemS) configReadConst(res http.ResponseWriter, req *http.Request) {
+	mSystem.neuron.Express.CodeResponse(res, 100, mSystem.configRedact(mSystem.neuron.Brain.JsonEncoder(mSystem.neuron.Brain.Const)))
+}
+
+//* 写入配置文件并重新加载 */
+func (mSystem *SystemS) configWriteFile(res http.ResponseWriter, req *http.Request) {
+	resBody, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		mSystem.neuron.Express.CodeResponse(res, 207, err)
+		return
+	}
+	if mSystem.neuron.Brain.JsonChecker(resBody) {
+		mSystem.neuron.Brain.FileWriter(mSystem.neuron.Brain.PathAbs("/config.json"), resBody)
+	}
+	mSystem.ConfigInit()
+	mSystem.neuron.Express.CodeResponse(res, 100, mSystem.configRedact(mSystem.neuron.Brain.JsonEncoder(mSystem.neuron.Brain.Const)))
 }
 
 //* 远程上传接口 */
 func (mSystem *SystemS) uploadInterface() {
+	mSystem.neuron.Express.UseService(mSystem.Const.root+"/Upload", mSystem.neuron.Express.Auth("admin"))
 	mSystem.mux.HandleFunc(mSystem.Const.root+"/Upload", func(res http.ResponseWriter, req *http.Request) {
 		mSystem.neuron.Express.ConstructInterface(res, req, mSystem.isStarted, func() {
 			query := mSystem.neuron.Express.Req2Query(req)
@@ -99,6 +134,11 @@ func (mSystem *SystemS) uploadInterface() {
 				for k := range query {
 					switch k {
 					case "AUTORUN":
+						// 未开启认证时拒绝远程执行
+						if !mSystem.neuron.Brain.Const.Auth.Open {
+							mSystem.neuron.Express.CodeResponse(res, 208, "Auth Closed -> AUTORUN Refused", "uploadInterface")
+							return
+						}
 						// 删除临时文件
 						mSystem.neuron.Brain.FileRemovAll(mSystem.neuron.Brain.PathAbs(fmt.Sprintf("%v/avatar", mSystem.neuron.Brain.Const.HTTPServer.UploadPath)))
 						code, data := mSystem.systemUpdate(res, req)
@@ -122,6 +162,23 @@ func (mSystem *SystemS) uploadInterface() {
 	})
 }
 
+//* 签发JWT接口[POST /Token,以API Key或HMAC认证后换取,JWT不可续签] */
+func (mSystem *SystemS) tokenInterface() {
+	mSystem.router.POST("/Token", func(res http.ResponseWriter, req *http.Request) {
+		identity := mSystem.neuron.Express.Identity(req)
+		if identity == nil {
+			mSystem.neuron.Express.CodeResponse(res, 208, "Auth Closed", "tokenInterface")
+			return
+		}
+		if identity.Method == model.AuthJWT {
+			mSystem.neuron.Express.CodeResponse(res, 208, "Token Renewal Refused -> Use API Key or HMAC", "tokenInterface")
+			return
+		}
+		code, data := mSystem.neuron.Express.AuthToken(identity.Subject, identity.Roles)
+		mSystem.neuron.Express.CodeResponse(res, code, data, "tokenInterface")
+	}, mSystem.neuron.Express.Auth())
+}
+
 //* ================================ PROCESS ================================ */
 
 //* 远程上传接口 */
@@ -223,6 +280,33 @@ func (mSystem *SystemS) systemUpdate(res http.ResponseWriter, req *http.Request)
 
 //* ================================ TOOL ================================ */
 
+//* 脱敏的配置副本[data -> 配置JSON,Auth/Security/Join秘钥及数据库口令] */
+func (mSystem *SystemS) configRedact(data []byte) interface{} {
+	config := mSystem.neuron.Brain.JsonDecoder(data)
+	for _, path := range configSecretPaths {
+		configMask(config, path)
+	}
+	return config
+}
+
+//* 按路径脱敏配置节点 */
+func configMask(node interface{}, path []string) {
+	obj, found := node.(map[string]interface{})
+	if !found || len(path) == 0 {
+		return
+	}
+	for k, v := range obj {
+		if path[0] != "*" && !strings.EqualFold(k, path[0]) {
+			continue
+		}
+		if len(path) > 1 {
+			configMask(v, path[1:])
+		} else if v != nil && v != "" {
+			obj[k] = "******"
+		}
+	}
+}
+
 //* LOG下划线日期文件名转日期格式 */
 func (mSystem *SystemS) splitFilename2Time(filename string) *model.TimeS {
 	filenameSlice := strings.Split(filename, "_")
@@ -315,16 +399,53 @@ func (mSystem *SystemS) clearLogLooperKiller() {
 	mSystem.neuron.Brain.ClearInterval(mSystem.StopChannel.clearLogLooperSC)
 }
 
+//* 定时压缩持久化日志 */
+func (mSystem *SystemS) compactLooper() {
+	// 运行中则避免重复
+	if !mSystem.neuron.Brain.CheckIsNull(mSystem.StopChannel.compactLooperSC) {
+		return
+	}
+	mSystem.StopChannel.compactLooperSC = make(chan bool)
+	go mSystem.neuron.Brain.SetInterval(func() (int, interface{}) {
+		hub := mSystem.neuron.Brain.Container.PersistHub
+		hub.Iterator(func(n int, k string, v interface{}) bool {
+			persist, found := v.(model.PersistI)
+			if !found {
+				return true
+			}
+			if err := persist.Err(); err != nil {
+				mSystem.neuron.Brain.MessageHandler(mSystem.Const.tag, "compactLooper["+k+"]", 205, err)
+			}
+			if err := persist.Compact(); err != nil {
+				mSystem.neuron.Brain.MessageHandler(mSystem.Const.tag, "compactLooper["+k+"]", 205, err)
+			}
+			return true
+		})
+		return 100, nil
+	}, func(code int, data interface{}) {
+		if code != 100 {
+			mSystem.neuron.Brain.MessageHandler(mSystem.Const.tag, "compactLooper[SetInterval]", code, data)
+		}
+	}, mSystem.neuron.Brain.Const.Persistence.CompactInterval, mSystem.StopChannel.compactLooperSC)
+}
+
+//* 析构定时压缩持久化日志 */
+func (mSystem *SystemS) compactLooperKiller() {
+	mSystem.neuron.Brain.ClearInterval(mSystem.StopChannel.compactLooperSC)
+}
+
 //* ================================ SERVICE ================================ */
 
 //* 构造服务 */
 func (mSystem *SystemS) service() {
 	mSystem.clearLogLooper()
+	mSystem.compactLooper()
 }
 
 //* 析构服务 */
 func (mSystem *SystemS) serviceKiller() {
 	mSystem.clearLogLooperKiller()
+	mSystem.compactLooperKiller()
 }
 
 //* ================================ PUBLIC ================================ */
@@ -0,0 +1,278 @@
+/**
+===========================================================================
+ * HTTP接口认证
+ * HTTP interface authentication
+ * API Key -> X-API-Key: <Secret> | Authorization: ApiKey <Secret>
+ * HMAC -> Authorization: HMAC <ClientId>:<HEX(HmacSha256(签名串, Secret))> + X-Timestamp: <毫秒>
+ *   签名串 -> METHOD\nRequestURI\nX-Timestamp\nHEX(Sha256(Body))
+ * JWT -> Authorization: Bearer <Token>[RS256,claims -> sub/roles/exp]
+ * 服务及路由以Auth(roles...)中间件声明所需角色[满足其一即可,空则仅需认证]
+===========================================================================
+*/
+package frame
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"model"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//* ================================ DEFINE ================================ */
+
+//* 上下文中的认证身份键 */
+const authIdentityKey = "Identity"
+
+//* JWT头部 */
+type jwtHeaderS struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+//* JWT声明 */
+type jwtClaimS struct {
+	Sub   string   `json:"sub"`
+	Roles []string `json:"roles,omitempty"`
+	Iss   string   `json:"iss,omitempty"`
+	Iat   int64    `json:"iat"`
+	Exp   int64    `json:"exp"`
+}
+
+//* ================================ PRIVATE ================================ */
+
+//* 读取PEM秘钥[按路径缓存] */
+func (express *ExpressS) authKey(pemPath string, private bool) interface{} {
+	if express.brain.CheckIsNull(pemPath) {
+		return nil
+	}
+	if key := express.authKeyHub.Get(pemPath); key != nil {
+		return key
+	}
+	code, data := express.brain.FileReader(express.brain.PathAbs(pemPath))
+	if code != 100 {
+		express.brain.MessageHandler(express.tag, "authKey -> FileReader", code, data)
+		return nil
+	}
+	var key interface{}
+	express.brain.SafeFunction(func() {
+		if private {
+			if priv := express.brain.Bytes2PrivateKey(data.([]byte)); priv != nil {
+				key = priv
+			}
+		} else if pub := express.brain.Bytes2PublicKey(data.([]byte)); pub != nil {
+			key = pub
+		}
+	})
+	if key != nil {
+		express.authKeyHub.Set(pemPath, key)
+	}
+	return key
+}
+
+//* API Key认证[常量时间比较] */
+func (express *ExpressS) authAPIKey(secret string) (*model.IdentityS, string) {
+	for clientId, client := range express.brain.Const.Auth.Clients {
+		if client.Secret != "" && hmac.Equal([]byte(client.Secret), []byte(secret)) {
+			return &model.IdentityS{Subject: clientId, Method: model.AuthAPIKey, Roles: client.Roles}, ""
+		}
+	}
+	return nil, "Invalid API Key"
+}
+
+//* HMAC签名认证 */
+func (express *ExpressS) authHMAC(ctx *ContextS, credential string) (*model.IdentityS, string) {
+	req := ctx.Req
+	kv := strings.SplitN(credential, ":", 2)
+	if len(kv) != 2 {
+		return nil, "Malformed HMAC Credential"
+	}
+	client, found := express.brain.Const.Auth.Clients[kv[0]]
+	if !found || client.Secret == "" {
+		return nil, "Unknown Client -> " + kv[0]
+	}
+	timestamp, err := strconv.ParseInt(req.Header.Get("X-Timestamp"), 10, 64)
+	if err != nil {
+		return nil, "Lack of X-Timestamp"
+	}
+	window := time.Duration(express.brain.Const.Auth.ReplayWindow) * time.Millisecond
+	sent := time.Unix(0, timestamp*int64(time.Millisecond))
+	if now := time.Now(); sent.Before(now.Add(-window)) || sent.After(now.Add(window)) {
+		return nil, "Timestamp Expired"
+	}
+	// 限制大小读取后还原请求体[中间件副本及接口持有的原请求]
+	var body []byte
+	if req.Body != nil {
+		express.bodyLimit(req)
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			if bodyTooLarge(err) {
+				return nil, "Body Too Large"
+			}
+			return nil, "Body Unreadable"
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if ctx.origin != nil && ctx.origin != req {
+			ctx.origin.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+	}
+	bodyHash := sha256.Sum256(body)
+	plain := fmt.Sprintf("%s\n%s\n%d\n%x", req.Method, req.URL.RequestURI(), timestamp, bodyHash)
+	if !hmac.Equal([]byte(express.brain.HmacSha256Encode([]byte(plain), client.Secret)), []byte(strings.ToLower(kv[1]))) {
+		return nil, "Invalid Signature"
+	}
+	if express.brain.isReplayed("auth@"+kv[0]+"@"+strings.ToLower(kv[1]), sent.Add(window)) {
+		return nil, "Signature Reused"
+	}
+	return &model.IdentityS{Subject: kv[0], Method: model.AuthHMAC, Roles: client.Roles}, ""
+}
+
+//* JWT认证[RS256] */
+func (express *ExpressS) authJWT(token string) (*model.IdentityS, string) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, "Malformed Token"
+	}
+	var header jwtHeaderS
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || json.Unmarshal(headerBytes, &header) != nil || header.Alg != "RS256" {
+		return nil, "Unsupported Token Header"
+	}
+	pub, found := express.authKey(express.brain.Const.Auth.PublicKey, false).(*rsa.PublicKey)
+	if !found {
+		return nil, "Token Verification Unavailable"
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !express.brain.RSAVerify(pub, []byte(parts[0]+"."+parts[1]), signature) {
+		return nil, "Invalid Token Signature"
+	}
+	var claim jwtClaimS
+	claimBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || json.Unmarshal(claimBytes, &claim) != nil || claim.Sub == "" {
+		return nil, "Malformed Token Claims"
+	}
+	expire := time.Unix(claim.Exp, 0)
+	if claim.Exp == 0 || time.Now().After(expire) {
+		return nil, "Token Expired"
+	}
+	return &model.IdentityS{Subject: claim.Sub, Method: model.AuthJWT, Roles: claim.Roles, Expire: expire}, ""
+}
+
+//* 解析请求凭证 */
+func (express *ExpressS) authenticate(ctx *ContextS) (*model.IdentityS, string) {
+	req := ctx.Req
+	if secret := req.Header.Get("X-API-Key"); secret != "" {
+		return express.authAPIKey(secret)
+	}
+	authorization := strings.SplitN(strings.TrimSpace(req.Header.Get("Authorization")), " ", 2)
+	if len(authorization) != 2 {
+		return nil, "Lack of Credential"
+	}
+	credential := strings.TrimSpace(authorization[1])
+	switch strings.ToLower(authorization[0]) {
+	case "apikey":
+		return express.authAPIKey(credential)
+	case "hmac":
+		return express.authHMAC(ctx, credential)
+	case "bearer":
+		return express.authJWT(credential)
+	}
+	return nil, "Unsupported Scheme -> " + authorization[0]
+}
+
+//* 角色判断[*拥有全部权限] */
+func authRole(owned []string, required []string) bool {
+	if len(required) == 0 {
+		return true
+	}
+	for _, v := range owned {
+		if v == "*" {
+			return true
+		}
+		for _, r := range required {
+			if v == r {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//* ================================ PUBLIC ================================ */
+
+//* 认证中间件[roles满足其一即可,同一请求仅认证一次] */
+func (express *ExpressS) Auth(roles ...string) MiddlewareF {
+	return func(ctx *ContextS, next func()) {
+		if !express.brain.Const.Auth.Open {
+			next()
+			return
+		}
+		identity, found := ctx.Get(authIdentityKey).(*model.IdentityS)
+		if !found {
+			var message string
+			if identity, message = express.authenticate(ctx); identity == nil {
+				express.brain.MessageHandler(express.tag, "Auth", 208, fmt.Sprintf("[%s %s] %s", ctx.Req.Method, ctx.Req.URL.Path, message))
+				ctx.Res.Header().Set("WWW-Authenticate", `Bearer realm="`+express.brain.Const.NeuronId+`"`)
+				express.StatusResponse(ctx.Res, http.StatusUnauthorized, 208, message, "Auth")
+				return
+			}
+			ctx.Set(authIdentityKey, identity)
+		}
+		if !authRole(identity.Roles, roles) {
+			express.brain.MessageHandler(express.tag, "Auth", 208, fmt.Sprintf("[%s %s] %s Lack of Role -> %v", ctx.Req.Method, ctx.Req.URL.Path, identity.Subject, roles))
+			express.StatusResponse(ctx.Res, http.StatusForbidden, 208, "Lack of Role -> "+strings.Join(roles, " | "), "Auth")
+			return
+		}
+		next()
+	}
+}
+
+//* 获取请求的认证身份[未认证返回nil] */
+func (express *ExpressS) Identity(req *http.Request) *model.IdentityS {
+	ctx := express.Context(req)
+	if ctx == nil {
+		return nil
+	}
+	identity, _ := ctx.Get(authIdentityKey).(*model.IdentityS)
+	return identity
+}
+
+//* 签发JWT[RS256,Auth.PrivateKey签名,Auth.TokenTTL有效] */
+func (express *ExpressS) AuthToken(subject string, roles []string) (int, interface{}) {
+	priv, found := express.authKey(express.brain.Const.Auth.PrivateKey, true).(*rsa.PrivateKey)
+	if !found {
+		return 208, "AuthToken -> Lack of PrivateKey"
+	}
+	now := time.Now()
+	claim := jwtClaimS{
+		Sub:   subject,
+		Roles: roles,
+		Iss:   express.brain.Const.NeuronId,
+		Iat:   now.Unix(),
+		Exp:   now.Add(time.Duration(express.brain.Const.Auth.TokenTTL) * time.Millisecond).Unix(),
+	}
+	headerBytes, _ := json.Marshal(jwtHeaderS{Alg: "RS256", Typ: "JWT"})
+	claimBytes, err := json.Marshal(claim)
+	if err != nil {
+		return 202, err
+	}
+	signing := base64.RawURLEncoding.EncodeToString(headerBytes) + "." + base64.RawURLEncoding.EncodeToString(claimBytes)
+	var signature []byte
+	express.brain.SafeFunction(func() {
+		signature = express.brain.RSASign(priv, []byte(signing))
+	})
+	if len(signature) == 0 {
+		return 208, "AuthToken -> RSASign Failed"
+	}
+	return 100, map[string]interface{}{
+		"Token":  signing + "." + base64.RawURLEncoding.EncodeToString(signature),
+		"Expire": time.Unix(claim.Exp, 0),
+	}
+}
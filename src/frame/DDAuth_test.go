@@ -0,0 +1,199 @@
+package frame
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+	"model"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+//* 配置认证客户端及JWT秘钥[秘钥直接写入缓存,无需PEM文件] */
+func authExpress(t *testing.T) *ExpressS {
+	express := &ExpressS{tag: "Express", brain: testBrain()}
+	auth := &express.brain.Const.Auth
+	express.brain.JsonDecoder([]byte(`{
+		"Open": true,
+		"Clients": {
+			"admin": {"Secret": "secret-admin", "Roles": ["admin"]},
+			"user": {"Secret": "secret-user", "Roles": ["user"]},
+			"root": {"Secret": "secret-root", "Roles": ["*"]}
+		},
+		"PublicKey": "public.pem",
+		"PrivateKey": "private.pem"
+	}`), auth)
+	priv, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	express.authKeyHub.Init("ExpressAuthKey")
+	express.authKeyHub.Set(auth.PublicKey, &priv.PublicKey)
+	express.authKeyHub.Set(auth.PrivateKey, priv)
+	return express
+}
+
+//* 构造HMAC签名请求 */
+func authHMACRequest(express *ExpressS, clientId string, secret string, body string, sent time.Time) *http.Request {
+	req := httptest.NewRequest("POST", "/Service/Call?a=1", strings.NewReader(body))
+	timestamp := sent.UnixNano() / int64(time.Millisecond)
+	plain := fmt.Sprintf("%s\n%s\n%d\n%x", req.Method, req.URL.RequestURI(), timestamp, sha256.Sum256([]byte(body)))
+	req.Header.Set("X-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("Authorization", "HMAC "+clientId+":"+express.brain.HmacSha256Encode([]byte(plain), secret))
+	return req
+}
+
+//* 构造JWT请求 */
+func authJWTRequest(t *testing.T, express *ExpressS, subject string, roles []string) *http.Request {
+	code, data := express.AuthToken(subject, roles)
+	if code != 100 {
+		t.Fatalf("AuthToken = %v %v", code, data)
+	}
+	req := httptest.NewRequest("GET", "/Service", nil)
+	req.Header.Set("Authorization", "Bearer "+data.(map[string]interface{})["Token"].(string))
+	return req
+}
+
+//* 执行认证中间件[返回响应及认证身份] */
+func authRun(express *ExpressS, req *http.Request, roles ...string) (*httptest.ResponseRecorder, *model.IdentityS) {
+	res := httptest.NewRecorder()
+	ctx := &ContextS{Res: res, Req: req, Start: time.Now(), origin: req, values: make(map[string]interface{})}
+	var identity *model.IdentityS
+	express.Auth(roles...)(ctx, func() {
+		identity, _ = ctx.Get(authIdentityKey).(*model.IdentityS)
+		res.WriteHeader(http.StatusOK)
+	})
+	return res, identity
+}
+
+func TestAuth(t *testing.T) {
+	cases := []struct {
+		name    string
+		req     func(t *testing.T, express *ExpressS) *http.Request
+		roles   []string
+		status  int
+		subject string
+		method  string
+		message string
+	}{
+		{"apiKeyHeader", func(t *testing.T, express *ExpressS) *http.Request {
+			req := httptest.NewRequest("GET", "/Service", nil)
+			req.Header.Set("X-API-Key", "secret-admin")
+			return req
+		}, []string{"admin"}, http.StatusOK, "admin", model.AuthAPIKey, ""},
+		{"apiKeyScheme", func(t *testing.T, express *ExpressS) *http.Request {
+			req := httptest.NewRequest("GET", "/Service", nil)
+			req.Header.Set("Authorization", "ApiKey secret-user")
+			return req
+		}, nil, http.StatusOK, "user", model.AuthAPIKey, ""},
+		{"apiKeyInvalid", func(t *testing.T, express *ExpressS) *http.Request {
+			req := httptest.NewRequest("GET", "/Service", nil)
+			req.Header.Set("X-API-Key", "secret-other")
+			return req
+		}, nil, http.StatusUnauthorized, "", "", "Invalid API Key"},
+		{"lackCredential", func(t *testing.T, express *ExpressS) *http.Request {
+			return httptest.NewRequest("GET", "/Service", nil)
+		}, nil, http.StatusUnauthorized, "", "", "Lack of Credential"},
+		{"unsupportedScheme", func(t *testing.T, express *ExpressS) *http.Request {
+			req := httptest.NewRequest("GET", "/Service", nil)
+			req.Header.Set("Authorization", "Basic YWRtaW46YWRtaW4=")
+			return req
+		}, nil, http.StatusUnauthorized, "", "", "Unsupported Scheme"},
+		{"hmac", func(t *testing.T, express *ExpressS) *http.Request {
+			return authHMACRequest(express, "admin", "secret-admin", `{"a":1}`, time.Now())
+		}, []string{"admin"}, http.StatusOK, "admin", model.AuthHMAC, ""},
+		{"hmacBadSignature", func(t *testing.T, express *ExpressS) *http.Request {
+			return authHMACRequest(express, "admin", "secret-user", `{"a":1}`, time.Now())
+		}, nil, http.StatusUnauthorized, "", "", "Invalid Signature"},
+		{"hmacBodyTampered", func(t *testing.T, express *ExpressS) *http.Request {
+			req := authHMACRequest(express, "admin", "secret-admin", `{"a":1}`, time.Now())
+			signed := httptest.NewRequest(req.Method, req.URL.RequestURI(), strings.NewReader(`{"a":2}`))
+			signed.Header = req.Header
+			return signed
+		}, nil, http.StatusUnauthorized, "", "", "Invalid Signature"},
+		{"hmacExpired", func(t *testing.T, express *ExpressS) *http.Request {
+			return authHMACRequest(express, "admin", "secret-admin", "", time.Now().Add(-time.Hour))
+		}, nil, http.StatusUnauthorized, "", "", "Timestamp Expired"},
+		{"hmacUnknownClient", func(t *testing.T, express *ExpressS) *http.Request {
+			return authHMACRequest(express, "other", "secret-admin", "", time.Now())
+		}, nil, http.StatusUnauthorized, "", "", "Unknown Client"},
+		{"jwt", func(t *testing.T, express *ExpressS) *http.Request {
+			return authJWTRequest(t, express, "admin", []string{"admin"})
+		}, []string{"admin"}, http.StatusOK, "admin", model.AuthJWT, ""},
+		{"jwtExpired", func(t *testing.T, express *ExpressS) *http.Request {
+			express.brain.Const.Auth.TokenTTL = -10000
+			return authJWTRequest(t, express, "admin", []string{"admin"})
+		}, nil, http.StatusUnauthorized, "", "", "Token Expired"},
+		{"jwtForeignKey", func(t *testing.T, express *ExpressS) *http.Request {
+			// 以其他私钥签发
+			priv, err := rsa.GenerateKey(rand.Reader, 1024)
+			if err != nil {
+				t.Fatal(err)
+			}
+			express.authKeyHub.Set(express.brain.Const.Auth.PrivateKey, priv)
+			return authJWTRequest(t, express, "admin", []string{"admin"})
+		}, nil, http.StatusUnauthorized, "", "", "Invalid Token Signature"},
+		{"jwtMalformed", func(t *testing.T, express *ExpressS) *http.Request {
+			req := httptest.NewRequest("GET", "/Service", nil)
+			req.Header.Set("Authorization", "Bearer a.b")
+			return req
+		}, nil, http.StatusUnauthorized, "", "", "Malformed Token"},
+		{"lackOfRole", func(t *testing.T, express *ExpressS) *http.Request {
+			req := httptest.NewRequest("GET", "/Service", nil)
+			req.Header.Set("X-API-Key", "secret-user")
+			return req
+		}, []string{"admin"}, http.StatusForbidden, "", "", "Lack of Role"},
+		{"anyRole", func(t *testing.T, express *ExpressS) *http.Request {
+			req := httptest.NewRequest("GET", "/Service", nil)
+			req.Header.Set("X-API-Key", "secret-user")
+			return req
+		}, []string{"admin", "user"}, http.StatusOK, "user", model.AuthAPIKey, ""},
+		{"wildcardRole", func(t *testing.T, express *ExpressS) *http.Request {
+			return authJWTRequest(t, express, "root", []string{"*"})
+		}, []string{"admin"}, http.StatusOK, "root", model.AuthJWT, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			express := authExpress(t)
+			res, identity := authRun(express, c.req(t, express), c.roles...)
+			if res.Code != c.status || !strings.Contains(res.Body.String(), c.message) {
+				t.Fatalf("response = %v %s, want %v %v", res.Code, res.Body, c.status, c.message)
+			}
+			if c.subject == "" {
+				return
+			}
+			if identity == nil || identity.Subject != c.subject || identity.Method != c.method {
+				t.Fatalf("identity = %+v, want %v[%v]", identity, c.subject, c.method)
+			}
+		})
+	}
+}
+
+func TestAuthSignatureReuse(t *testing.T) {
+	express := authExpress(t)
+	sent := time.Now()
+	if res, _ := authRun(express, authHMACRequest(express, "admin", "secret-admin", "x", sent)); res.Code != http.StatusOK {
+		t.Fatalf("first status = %v, want %v", res.Code, http.StatusOK)
+	}
+	if res, _ := authRun(express, authHMACRequest(express, "admin", "secret-admin", "x", sent)); res.Code != http.StatusUnauthorized || !strings.Contains(res.Body.String(), "Signature Reused") {
+		t.Fatalf("reused response = %v %s, want %v Signature Reused", res.Code, res.Body, http.StatusUnauthorized)
+	}
+	// 新时间戳重新签名可通过
+	if res, _ := authRun(express, authHMACRequest(express, "admin", "secret-admin", "x", sent.Add(time.Millisecond))); res.Code != http.StatusOK {
+		t.Fatalf("resigned status = %v, want %v", res.Code, http.StatusOK)
+	}
+}
+
+func TestAuthClosed(t *testing.T) {
+	express := authExpress(t)
+	express.brain.Const.Auth.Open = false
+	res, identity := authRun(express, httptest.NewRequest("GET", "/Service", nil), "admin")
+	if res.Code != http.StatusOK || identity != nil {
+		t.Fatalf("closed auth = %v %+v, want %v without identity", res.Code, identity, http.StatusOK)
+	}
+}
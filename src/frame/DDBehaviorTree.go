@@ -30,6 +30,7 @@
 package frame
 
 import (
+	"fmt"
 	"model"
 	"reflect"
 )
@@ -54,6 +55,51 @@ func (mBehaviorTree *BehaviorTreeS) main() {
 
 }
 
+//* 初始化森林容器[开启持久化则重放/data/{tag}下的预写日志] */
+func (mBehaviorTree *BehaviorTreeS) forestInit(tag string) (model.QueueI, model.MapI) {
+	if mBehaviorTree.brain.Const.Persistence.BehaviorForest {
+		code, queue := mBehaviorTree.brain.PersistQueue(tag+"/UUIDQ", model.PersistCodecS{
+			Encode: func(v interface{}) ([]byte, error) {
+				return []byte(fmt.Sprintf("%v", v)), nil
+			},
+			Decode: func(b []byte) (interface{}, error) {
+				return string(b), nil
+			},
+		})
+		if code != 100 {
+			mBehaviorTree.brain.MessageHandler(mBehaviorTree.tag, "forestInit[PersistQueue]", code, queue)
+		} else {
+			code, trees := mBehaviorTree.brain.PersistMap(tag+"/Trees", model.PersistCodecS{
+				Encode: func(v interface{}) ([]byte, error) {
+					tree, found := v.(*model.BehaviorTreeS)
+					if !found {
+						return nil, fmt.Errorf("treeCodec -> DataType Error")
+					}
+					code, data := mBehaviorTree.Tree2Json(tree)
+					if code != 100 {
+						return nil, fmt.Errorf("treeCodec -> %v", data)
+					}
+					return data.([]byte), nil
+				},
+				Decode: func(b []byte) (interface{}, error) {
+					code, data := mBehaviorTree.Json2Tree(b)
+					if code != 100 {
+						return nil, fmt.Errorf("treeCodec -> %v", data)
+					}
+					return data, nil
+				},
+			})
+			if code == 100 {
+				return queue.(model.QueueI), trees.(model.MapI)
+			}
+			mBehaviorTree.brain.MessageHandler(mBehaviorTree.tag, "forestInit[PersistMap]", code, trees)
+		}
+	}
+	trees := new(model.SyncMapHub)
+	trees.Init(tag)
+	return new(model.QueueS).New(), trees
+}
+
 //* ================================ PUBLIC ================================ */
 
 //* 构造本体 */
@@ -71,8 +117,7 @@ func (mBehaviorTree *BehaviorTreeS) NewForest(tags ...string) model.BehaviorFore
 		tag = tags[0]
 	}
 	forest := model.BehaviorForestS{}
-	forest.UUIDQ = new(model.QueueS).New()
-	forest.Trees.Init(tag)
+	forest.UUIDQ, forest.Trees = mBehaviorTree.forestInit(tag)
 	forest.ErrorQ = new(model.QueueS).New(mBehaviorTree.brain.Const.BehaviorTree.ErrorQLen)
 	return forest
 }
@@ -0,0 +1,361 @@
+/**
+===========================================================================
+ * 请求绑定与校验
+ * Request binding and validation
+ * 来源 -> 查询参数 < 表单/Multipart < 路径参数,JSON/XML请求体最后解码覆盖
+ * bind:"name" -> 参数名[缺省依次取json标签、首字母小写的字段名,忽略大小写匹配,"-"则跳过]
+ * validate:"required,min=1,max=10,enum=a|b|c,regex=^[a-z]+$"
+ *   min/max -> 数值比较大小,字符串及切片比较长度
+ *   regex须置于最后[可包含逗号]
+ *   空值仅校验required
+ * 校验失败返回207及[]model.FieldErrorS
+ * 请求体超出HTTPServer.MaxBody返回207
+===========================================================================
+*/
+package frame
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"model"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//* ================================ DEFINE ================================ */
+
+var fileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+
+//* ================================ PRIVATE ================================ */
+
+//* 限制请求体大小[HTTPServer.MaxBody] */
+func (express *ExpressS) bodyLimit(req *http.Request) {
+	if maxBody := express.brain.Const.HTTPServer.MaxBody; req.Body != nil && maxBody > 0 {
+		req.Body = http.MaxBytesReader(nil, req.Body, maxBody)
+	}
+}
+
+//* 请求体是否超出大小限制 */
+func bodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+//* 参数名[bind -> json -> 首字母小写的字段名] */
+func bindName(field reflect.StructField) string {
+	if name := field.Tag.Get("bind"); name != "" {
+		return name
+	}
+	if name := strings.Split(field.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+		return name
+	}
+	return strings.ToLower(field.Name[:1]) + field.Name[1:]
+}
+
+//* 查找参数[精确匹配优先,其次忽略大小写] */
+func bindLookup(values map[string][]string, name string) ([]string, bool) {
+	if v, found := values[name]; found {
+		return v, true
+	}
+	for k, v := range values {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+//* 字符串转换为字段类型 */
+func bindScalar(value reflect.Value, raw string) error {
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(raw, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(raw, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+	return nil
+}
+
+//* 写入结构体字段 */
+func (express *ExpressS) bindValues(target reflect.Value, values map[string][]string, files map[string][]*multipart.FileHeader, errs *[]model.FieldErrorS) {
+	targetType := target.Type()
+	for i := 0; i < targetType.NumField(); i++ {
+		field := targetType.Field(i)
+		value := target.Field(i)
+		if field.Anonymous && value.Kind() == reflect.Struct {
+			express.bindValues(value, values, files, errs)
+			continue
+		}
+		if field.PkgPath != "" || field.Tag.Get("bind") == "-" {
+			continue
+		}
+		name := bindName(field)
+		// 上传文件
+		if field.Type == fileHeaderType || (field.Type.Kind() == reflect.Slice && field.Type.Elem() == fileHeaderType) {
+			headers, found := files[name]
+			if !found || len(headers) == 0 {
+				continue
+			}
+			if field.Type == fileHeaderType {
+				value.Set(reflect.ValueOf(headers[0]))
+			} else {
+				value.Set(reflect.ValueOf(headers))
+			}
+			continue
+		}
+		raws, found := bindLookup(values, name)
+		if !found || len(raws) == 0 {
+			continue
+		}
+		var err error
+		if value.Kind() == reflect.Slice && value.Type().Elem().Kind() != reflect.Uint8 {
+			slice := reflect.MakeSlice(value.Type(), len(raws), len(raws))
+			for k, v := range raws {
+				if err = bindScalar(slice.Index(k), v); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				value.Set(slice)
+			}
+		} else {
+			err = bindScalar(value, raws[0])
+		}
+		if err != nil {
+			*errs = append(*errs, model.FieldErrorS{Field: name, Rule: "type", Message: err.Error()})
+		}
+	}
+}
+
+//* 拆分校验规则[regex之后的内容整体作为表达式] */
+func validateRules(tag string) []string {
+	rules := make([]string, 0)
+	for tag != "" {
+		if strings.HasPrefix(tag, "regex=") {
+			rules = append(rules, tag)
+			break
+		}
+		index := strings.Index(tag, ",")
+		if index == -1 {
+			rules = append(rules, strings.TrimSpace(tag))
+			break
+		}
+		rules = append(rules, strings.TrimSpace(tag[:index]))
+		tag = strings.TrimSpace(tag[index+1:])
+	}
+	return rules
+}
+
+//* 数值或长度 */
+func validateSize(value reflect.Value) (float64, bool) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), true
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return float64(value.Len()), true
+	}
+	return 0, false
+}
+
+//* 校验单条规则 */
+func validateRule(value reflect.Value, rule string) string {
+	kv := strings.SplitN(rule, "=", 2)
+	arg := ""
+	if len(kv) == 2 {
+		arg = kv[1]
+	}
+	// 切片逐个校验enum/regex
+	elems := []reflect.Value{value}
+	if value.Kind() == reflect.Slice && value.Type().Elem().Kind() != reflect.Uint8 && (kv[0] == "enum" || kv[0] == "regex") {
+		elems = elems[:0]
+		for i := 0; i < value.Len(); i++ {
+			elems = append(elems, value.Index(i))
+		}
+	}
+	switch kv[0] {
+	case "min", "max":
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return "invalid rule " + rule
+		}
+		size, found := validateSize(value)
+		if !found {
+			return ""
+		}
+		if kv[0] == "min" && size < limit {
+			return "must be at least " + arg
+		}
+		if kv[0] == "max" && size > limit {
+			return "must be at most " + arg
+		}
+	case "enum":
+		for _, elem := range elems {
+			matched := false
+			for _, v := range strings.Split(arg, "|") {
+				if fmt.Sprint(elem.Interface()) == v {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return "must be one of " + arg
+			}
+		}
+	case "regex":
+		pattern, err := regexp.Compile(arg)
+		if err != nil {
+			return "invalid rule " + rule
+		}
+		for _, elem := range elems {
+			if !pattern.MatchString(fmt.Sprint(elem.Interface())) {
+				return "must match " + arg
+			}
+		}
+	}
+	return ""
+}
+
+//* 校验结构体字段 */
+func (express *ExpressS) validate(target reflect.Value, errs *[]model.FieldErrorS) {
+	targetType := target.Type()
+	for i := 0; i < targetType.NumField(); i++ {
+		field := targetType.Field(i)
+		value := target.Field(i)
+		if field.Anonymous && value.Kind() == reflect.Struct {
+			express.validate(value, errs)
+			continue
+		}
+		tag := field.Tag.Get("validate")
+		if field.PkgPath != "" || tag == "" {
+			continue
+		}
+		name := bindName(field)
+		isZero := value.IsZero()
+		for _, rule := range validateRules(tag) {
+			message := ""
+			if rule == "required" {
+				if isZero {
+					message = "is required"
+				}
+			} else if !isZero {
+				message = validateRule(value, rule)
+			}
+			if message != "" {
+				*errs = append(*errs, model.FieldErrorS{Field: name, Rule: rule, Message: message})
+				break
+			}
+		}
+	}
+}
+
+//* ================================ PUBLIC ================================ */
+
+//* 绑定请求参数至结构体并校验[target -> *struct,失败返回207及[]model.FieldErrorS] */
+func (express *ExpressS) Bind(req *http.Request, target interface{}) (int, interface{}) {
+	targetValue := reflect.ValueOf(target)
+	if targetValue.Kind() != reflect.Ptr || targetValue.Elem().Kind() != reflect.Struct {
+		return 221, "Bind -> Target must be *struct"
+	}
+	errs := make([]model.FieldErrorS, 0)
+	values := url.Values{}
+	for k, v := range req.URL.Query() {
+		values[k] = v
+	}
+	var files map[string][]*multipart.FileHeader
+	var body []byte
+	express.bodyLimit(req)
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	switch mediaType {
+	case "multipart/form-data":
+		if err := req.ParseMultipartForm(32 << 20); err != nil {
+			if bodyTooLarge(err) {
+				return 207, []model.FieldErrorS{{Rule: "size", Message: err.Error()}}
+			}
+			return 207, []model.FieldErrorS{{Rule: "multipart", Message: err.Error()}}
+		}
+		for k, v := range req.MultipartForm.Value {
+			values[k] = v
+		}
+		files = req.MultipartForm.File
+	case "application/x-www-form-urlencoded":
+		if err := req.ParseForm(); err != nil {
+			if bodyTooLarge(err) {
+				return 207, []model.FieldErrorS{{Rule: "size", Message: err.Error()}}
+			}
+			return 207, []model.FieldErrorS{{Rule: "form", Message: err.Error()}}
+		}
+		for k, v := range req.PostForm {
+			values[k] = v
+		}
+	case "application/json", "application/xml", "text/xml":
+		if req.Body != nil {
+			buf, err := ioutil.ReadAll(req.Body)
+			if bodyTooLarge(err) {
+				return 207, []model.FieldErrorS{{Rule: "size", Message: err.Error()}}
+			}
+			if err != nil {
+				return 216, err
+			}
+			body = buf
+		}
+	}
+	for k, v := range express.Params(req) {
+		values[k] = []string{v}
+	}
+	express.bindValues(targetValue.Elem(), values, files, &errs)
+	if len(body) > 0 {
+		var err error
+		if mediaType == "application/json" {
+			err = json.Unmarshal(body, target)
+		} else {
+			err = xml.Unmarshal(body, target)
+		}
+		if err != nil {
+			errs = append(errs, model.FieldErrorS{Rule: strings.TrimPrefix(mediaType[strings.Index(mediaType, "/"):], "/"), Message: err.Error()})
+		}
+	}
+	if len(errs) == 0 {
+		express.validate(targetValue.Elem(), &errs)
+	}
+	if len(errs) > 0 {
+		return 207, errs
+	}
+	return 100, nil
+}
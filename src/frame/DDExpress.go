@@ -6,8 +6,11 @@
 package frame
 
 import (
+	"bufio"
 	"bytes"
 	"crypto/tls"
+	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"io"
 	"model"
@@ -17,17 +20,119 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 //* ================================ DEFINE ================================ */
 
 type ExpressS struct {
-	tag   string
-	brain *BrainS
+	tag    string
+	brain  *BrainS
+	neuron *NeuronS
 
 	// Express[Ws]连接容器
 	hub model.SyncMapHub
+	// 路由轮询计数
+	routeIndex model.SyncMapHub /* map[Route]*uint32 */
+	// Websocket连接状态
+	connMutex  sync.RWMutex
+	connStates map[*websocket.Conn]model.ConnStateS
+	// 已注册的远程调用
+	rpcHub model.SyncMapHub /* map[Service.Function]model.RPCHandlerS */
+	// Receiver已接收未执行的EVAL预占额度
+	creditMutex   sync.Mutex
+	creditPending map[string]int    /* map[Service]Count */
+	creditHolds   map[string]string /* map[MessageId]Service */
+	// 反向隧道
+	tunnelHub model.SyncMapHub /* map[TunnelId]tunnelListenerS */
+	streamHub model.SyncMapHub /* map[StreamKey]*tunnelStreamS */
+	// 已关闭的隧道流
+	streamClosed  model.SyncMapHub /* map[StreamKey]bool */
+	streamClosedQ *model.QueueS
+	// 分块文件传输
+	transferSendHub model.SyncMapHub /* map[TransferId]chan *model.GMessageS */
+	transferRecvHub model.SyncMapHub /* map[TransferId]*transferRecvS */
+	transferPullHub model.SyncMapHub /* map[TransferId]*transferPullS */
+	// 指令追踪
+	traceMutex    sync.Mutex
+	traceFile     *os.File
+	traceWriter   *bufio.Writer
+	traceSize     int64
+	traceFlushing bool
+	traceHub      model.SyncMapHub /* map[Trace][]model.TraceHopS */
+	traceQ        *model.QueueS
+	// Receiver端已接收指令的追踪编号
+	traceIds model.SyncMapHub /* map[MessageId]Trace */
+	traceIdQ *model.QueueS
+	// 中间件[全局及服务根路径]
+	middlewareMutex sync.RWMutex
+	middlewares     []MiddlewareF
+	middlewareHub   model.SyncMapHub /* map[Root][]MiddlewareF */
+	// 认证秘钥缓存
+	authKeyHub model.SyncMapHub /* map[PEMPath]*rsa.PublicKey | *rsa.PrivateKey */
+}
+
+//* XML响应格式 */
+type xmlMessageS struct {
+	XMLName xml.Name `xml:"Message"`
+	Code    int
+	Message string
+	Data    xmlValueS
+}
+
+//* XML通用值[Data经JSON转换,map -> 子元素,slice -> Item] */
+type xmlValueS struct {
+	value interface{}
+}
+
+//* XML元素名 */
+var xmlNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+func (v xmlValueS) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	switch value := v.value.(type) {
+	case nil:
+		return e.EncodeElement("", start)
+	case map[string]interface{}:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			// 键不是合法元素名则以Entry[Key]表示
+			child := xml.StartElement{Name: xml.Name{Local: k}}
+			if !xmlNameRegexp.MatchString(k) {
+				child = xml.StartElement{Name: xml.Name{Local: "Entry"}, Attr: []xml.Attr{{Name: xml.Name{Local: "Key"}, Value: k}}}
+			}
+			if err := e.EncodeElement(xmlValueS{value[k]}, child); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	case []interface{}:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		for _, vv := range value {
+			if err := e.EncodeElement(xmlValueS{vv}, xml.StartElement{Name: xml.Name{Local: "Item"}}); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	default:
+		return e.EncodeElement(fmt.Sprint(value), start)
+	}
 }
 
 //* ================================ INNER INTERFACE ================================ */
@@ -63,6 +168,7 @@ func (express *ExpressS) WSHandler(ws *websocket.Conn, wsI model.WebsocketI) {
 		express.brain.LogGenerater(model.LogTrace, express.tag, hub.Tag, fmt.Sprintf("Exit Customer -> [%v] Count -> [%v]", ws.Request().RemoteAddr, hub.Len()))
 		ws.Close()
 		hub.Del(ws.Request().RemoteAddr)
+		express.WSConnDel(ws)
 	}()
 	// Init Customer
 	//* 此处Tag为空即为广义连接者 */
@@ -116,6 +222,25 @@ func (express *ExpressS) WSHandler(ws *websocket.Conn, wsI model.WebsocketI) {
 
 func (express *ExpressS) main() {
 	express.hub.Init("ExpressTunnel")
+	express.routeIndex.Init("ExpressRouteIndex")
+	express.connStates = make(map[*websocket.Conn]model.ConnStateS)
+	express.rpcHub.Init("ExpressRPC")
+	express.creditPending = make(map[string]int)
+	express.creditHolds = make(map[string]string)
+	express.tunnelHub.Init("ExpressTunnels")
+	express.streamHub.Init("ExpressStream")
+	express.streamClosed.Init("ExpressStreamClosed")
+	express.streamClosedQ = new(model.QueueS).New()
+	express.transferSendHub.Init("ExpressTransferSend")
+	express.transferRecvHub.Init("ExpressTransferRecv")
+	express.transferPullHub.Init("ExpressTransferPull")
+	express.traceHub.Init("ExpressTrace")
+	express.traceQ = new(model.QueueS).New()
+	express.traceIds.Init("ExpressTraceIds")
+	express.traceIdQ = new(model.QueueS).New()
+	express.middlewareHub.Init("ExpressMiddleware")
+	express.authKeyHub.Init("ExpressAuthKey")
+	express.Use(express.MiddlewareLog(), express.MiddlewareHeader(), express.MiddlewareCORS())
 }
 
 //* TCP服务端处理程序 */
@@ -376,6 +501,7 @@ func (express *ExpressS) uart2UDPRemoteConn(remote serial.OpenOptions, localConn
 func (express *ExpressS) Ontology(neuron *NeuronS) *ExpressS {
 	express.tag = "Express"
 	express.brain = neuron.Brain
+	express.neuron = neuron
 	express.brain.SafeFunction(express.main)
 	return express
 }
@@ -427,14 +553,10 @@ func (express *ExpressS) Middleware(res http.ResponseWriter, req *http.Request,
 	//if express.brain.Const.HTTPS.Open && u.Port() == "80" {
 	//	http.Redirect(res, req, express.Req2Url(req), http.StatusMovedPermanently)
 	//}
-	// Log
-	express.brain.LogGenerater(model.LogTrace, express.tag, "Middleware", fmt.Sprintf("[Visitor] => %s [Resource] => %s %s", req.RemoteAddr, req.Method, req.URL))
-	// Header
-	res.Header().Set("X-Powered-By", express.brain.Const.HTTPServer.XPoweredBy)
-	if express.brain.Const.HTTPServer.ACAO {
-		res.Header().Set("Access-Control-Allow-Origin", "*")
-	}
-	next()
+	// 全局及服务中间件[见DDMiddleware.go]
+	express.middleware(res, req, func(ctx *ContextS) {
+		next()
+	})
 }
 
 //* 获取Requst中的地址 */
@@ -483,7 +605,8 @@ func (express *ExpressS) Req2Query(req *http.Request) url.Values {
 			return nil
 		}
 		return query.Query()
-	} else if req.Method == "POST" {
+	} else {
+		// POST/PUT/PATCH/DELETE表单[Query与Body合并]
 		err := req.ParseForm()
 		if err != nil {
 			express.brain.MessageHandler(express.tag, "Req2Query", 207, err)
@@ -491,7 +614,6 @@ func (express *ExpressS) Req2Query(req *http.Request) url.Values {
 		}
 		return req.Form
 	}
-	return nil
 }
 
 //* URL对象转化 */
@@ -521,8 +643,13 @@ func (express *ExpressS) Url2HostPath(uStr string) (string, string) {
 	return scheme + u.Host, u.Path + query
 }
 
-//* Response -> 通用JSON格式 */
+//* Response -> 通用格式[错误码映射HTTP状态] */
 func (express *ExpressS) CodeResponse(res http.ResponseWriter, code int, data ...interface{}) {
+	express.StatusResponse(res, express.HTTPStatus(code), code, data...)
+}
+
+//* Response -> 指定HTTP状态的通用格式[按Accept输出JSON/XML/文本,Always200则状态恒为200] */
+func (express *ExpressS) StatusResponse(res http.ResponseWriter, status int, code int, data ...interface{}) {
 	var content interface{}
 	var function string
 	// data[0] -> content
@@ -538,11 +665,122 @@ func (express *ExpressS) CodeResponse(res http.ResponseWriter, code int, data ..
 		function = data[1].(string)
 	}
 	msg := express.brain.MessageHandler(express.tag, function, code, content)
-	res.Write(express.brain.JsonEncoder(msg))
+	var body []byte
+	switch express.negotiate(res) {
+	case "xml":
+		res.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		body = express.xmlMessage(msg)
+	case "text":
+		res.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		body = express.textMessage(msg)
+	default:
+		res.Header().Set("Content-Type", "application/json; charset=utf-8")
+		body = express.brain.JsonEncoder(msg)
+	}
+	res.Header().Add("Vary", "Accept")
+	if !express.brain.Const.HTTPServer.Always200 && status != http.StatusOK {
+		res.WriteHeader(status)
+	}
+	res.Write(body)
 }
 
-//* Response -> 通用错误格式 */
+//* 错误码映射HTTP状态[1xx状态,2xx框架错误,3xx数据库,4xx Redis] */
+func (express *ExpressS) HTTPStatus(code int) int {
+	switch code {
+	case 103, 201, 223, 300, 400:
+		return http.StatusServiceUnavailable
+	case 104:
+		return http.StatusGatewayTimeout
+	case 202, 203, 207, 209, 212, 213, 219, 221:
+		return http.StatusBadRequest
+	case 208:
+		return http.StatusUnauthorized
+	case 210, 211, 214, 222:
+		return http.StatusBadGateway
+	case 220:
+		return http.StatusNotFound
+	}
+	if code < 200 {
+		return http.StatusOK
+	}
+	return http.StatusInternalServerError
+}
+
+//* 内容协商[json | xml | text | html,未携带Accept则为json] */
+func (express *ExpressS) negotiate(res http.ResponseWriter) string {
+	wrapped, found := res.(*responseS)
+	if !found {
+		return "json"
+	}
+	result, best := "json", 0.0
+	for _, v := range strings.Split(wrapped.req.Header.Get("Accept"), ",") {
+		parts := strings.Split(v, ";")
+		q := 1.0
+		for _, vv := range parts[1:] {
+			if vv = strings.TrimSpace(vv); strings.HasPrefix(vv, "q=") {
+				q, _ = strconv.ParseFloat(vv[2:], 64)
+			}
+		}
+		format := ""
+		switch strings.ToLower(strings.TrimSpace(parts[0])) {
+		case "application/json", "application/*", "*/*":
+			format = "json"
+		case "application/xml", "text/xml":
+			format = "xml"
+		case "text/plain":
+			format = "text"
+		case "text/html":
+			format = "html"
+		}
+		if format != "" && q > best {
+			result, best = format, q
+		}
+	}
+	return result
+}
+
+//* MessageS -> XML */
+func (express *ExpressS) xmlMessage(msg model.MessageS) []byte {
+	var value interface{}
+	if buf, err := json.Marshal(msg.Data); err == nil {
+		decoder := json.NewDecoder(bytes.NewReader(buf))
+		decoder.UseNumber()
+		decoder.Decode(&value)
+	} else {
+		value = fmt.Sprint(msg.Data)
+	}
+	return append([]byte(xml.Header), express.brain.XMLEncoder(xmlMessageS{Code: msg.Code, Message: msg.Message, Data: xmlValueS{value}})...)
+}
+
+//* MessageS -> 文本[首行Code Message,其后为Data] */
+func (express *ExpressS) textMessage(msg model.MessageS) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("%d %s\n", msg.Code, msg.Message))
+	switch data := msg.Data.(type) {
+	case nil:
+	case string:
+		buf.WriteString(data + "\n")
+	default:
+		buf.Write(express.brain.JsonEncoder(data, true))
+		buf.WriteString("\n")
+	}
+	return buf.Bytes()
+}
+
+//* Response -> 通用错误格式[浏览器返回HTML页面,其余按Accept输出] */
 func (express *ExpressS) ErrorResponse(res http.ResponseWriter, code int) {
+	if code != 500 {
+		code = 404
+	}
+	if _, found := res.(*responseS); found && express.negotiate(res) != "html" {
+		errorCode := 207
+		if code == 500 {
+			errorCode = 204
+		}
+		express.StatusResponse(res, code, errorCode, http.StatusText(code), "ErrorResponse")
+		return
+	}
+	res.Header().Set("Content-Type", "text/html; charset=utf-8")
 	switch code {
 	case 500:
 		res.WriteHeader(code)
@@ -550,18 +788,23 @@ func (express *ExpressS) ErrorResponse(res http.ResponseWriter, code int) {
 		break
 	default:
 		res.WriteHeader(404)
-		res.Write([]byte("<body style='margin:0;overflow-y:auto;'><img style='width:100%;' src='/error/404.gif' onerror='javascript:document.body.innerHTML = \"<h1>500 Server Error</h1>\"'></body>"))
+		res.Write([]byte("<body style='margin:0;overflow-y:auto;'><img style='width:100%;' src='/error/404.gif' onerror='javascript:document.body.innerHTML = \"<h1>404 Not Found</h1>\"'></body>"))
 		break
 	}
 }
 
 //* REQ&RES -> 构建通用接口 */
 func (express *ExpressS) ConstructInterface(res http.ResponseWriter, req *http.Request, isStarted bool, next func(), callbacks ...func(err interface{})) {
+	express.constructInterface(res, req, isStarted, func(ctx *ContextS) {
+		next()
+	}, callbacks...)
+}
+
+//* REQ&RES -> 构建通用接口[next获取请求上下文] */
+func (express *ExpressS) constructInterface(res http.ResponseWriter, req *http.Request, isStarted bool, next func(ctx *ContextS), callbacks ...func(err interface{})) {
 	if isStarted {
 		express.brain.SafeFunction(func() {
-			express.Middleware(res, req, func() {
-				next()
-			})
+			express.middleware(res, req, next)
 		}, func(err interface{}) {
 			if err == nil {
 				return
@@ -575,36 +818,40 @@ func (express *ExpressS) ConstructInterface(res http.ResponseWriter, req *http.R
 	}
 }
 
-//* Service -> 构建通用服务 */
+//* Service -> 构建通用服务[启停服务需admin角色] */
 func (express *ExpressS) ConstructService(service model.ExpressI, servicePath string, res http.ResponseWriter, req *http.Request) {
 	express.brain.SafeFunction(func() {
-		express.Middleware(res, req, func() {
+		express.middleware(res, req, func(ctx *ContextS) {
 			query := express.Req2Query(req)
 			neuronId := express.brain.Const.NeuronId
-			if !express.brain.CheckIsNull(query[neuronId+"-start"]) {
-				if !service.IsStarted() {
-					// 开启服务
-					service.StartService()
+			start := !express.brain.CheckIsNull(query[neuronId+"-start"])
+			if !start && express.brain.CheckIsNull(query[neuronId+"-stop"]) {
+				// 服务状态
+				if service.IsStarted() {
 					express.CodeResponse(res, 101, "[Visitor] => "+req.RemoteAddr)
 				} else {
-					http.Redirect(res, req, servicePath, http.StatusFound)
+					express.CodeResponse(res, 102, "[Visitor] => "+req.RemoteAddr)
 				}
-			} else if !express.brain.CheckIsNull(query[neuronId+"-stop"]) {
-				if service.IsStarted() {
+				return
+			}
+			// 启停服务需admin角色
+			express.Auth("admin")(ctx, func() {
+				if start {
+					if !service.IsStarted() {
+						// 开启服务
+						service.StartService()
+						express.CodeResponse(res, 101, "[Visitor] => "+req.RemoteAddr)
+					} else {
+						http.Redirect(res, req, servicePath, http.StatusFound)
+					}
+				} else if service.IsStarted() {
 					// 关闭服务
 					service.StopService()
 					express.CodeResponse(res, 102, "[Visitor] => "+req.RemoteAddr)
 				} else {
 					http.Redirect(res, req, servicePath, http.StatusFound)
 				}
-			} else {
-				// 服务状态
-				if service.IsStarted() {
-					express.CodeResponse(res, 101, "[Visitor] => "+req.RemoteAddr)
-				} else {
-					express.CodeResponse(res, 102, "[Visitor] => "+req.RemoteAddr)
-				}
-			}
+			})
 		})
 	}, func(err interface{}) {
 		if err == nil {
@@ -616,6 +863,89 @@ func (express *ExpressS) ConstructService(service model.ExpressI, servicePath st
 
 //* ================================ SOCKET ================================ */
 
+//* 获取连接状态 */
+func (express *ExpressS) WSConn(conn *websocket.Conn) model.ConnStateS {
+	express.connMutex.RLock()
+	defer express.connMutex.RUnlock()
+	return express.connStates[conn]
+}
+
+//* 更新连接状态 */
+func (express *ExpressS) WSConnUpdate(conn *websocket.Conn, update func(state *model.ConnStateS)) {
+	express.connMutex.Lock()
+	defer express.connMutex.Unlock()
+	state := express.connStates[conn]
+	update(&state)
+	express.connStates[conn] = state
+}
+
+//* 删除连接状态 */
+func (express *ExpressS) WSConnDel(conn *websocket.Conn) {
+	express.connMutex.Lock()
+	delete(express.connStates, conn)
+	express.connMutex.Unlock()
+	express.tunnelRelease(conn)
+}
+
+//* 获取连接编码格式[未协商则为文本格式] */
+func (express *ExpressS) WSCodec(conn *websocket.Conn) string {
+	if codec := express.WSConn(conn).Codec; codec != "" {
+		return codec
+	}
+	return model.CodecText
+}
+
+//* 设置连接编码格式 */
+func (express *ExpressS) WSCodecSet(conn *websocket.Conn, codec string) {
+	express.WSConnUpdate(conn, func(state *model.ConnStateS) {
+		state.Codec = codec
+	})
+}
+
+//* 记录对端秘钥编号[回复使用相同秘钥] */
+func (express *ExpressS) WSKeySet(conn *websocket.Conn, keyId string) {
+	if keyId == "" || express.WSConn(conn).KeyId == keyId {
+		return
+	}
+	express.WSConnUpdate(conn, func(state *model.ConnStateS) {
+		state.KeyId = keyId
+	})
+}
+
+//* 选择双方均支持的编码格式[HELLO协商] */
+func (express *ExpressS) WSCodecSelect(offers []interface{}) string {
+	accepted := make(map[string]bool)
+	for _, v := range offers {
+		if offer, found := v.(string); found {
+			accepted[offer] = true
+		}
+	}
+	switch {
+	case express.brain.Const.WSParam.Codec == model.CodecBinary && accepted[model.CodecBinary]:
+		return model.CodecBinary
+	case accepted[model.CodecTextTrace]:
+		return model.CodecTextTrace
+	}
+	return model.CodecText
+}
+
+//* 按连接编码格式及秘钥加密发送GMessage */
+func (express *ExpressS) WSWrite(conn *websocket.Conn, gMsg *model.GMessageS) error {
+	if conn == nil {
+		return fmt.Errorf("WSWrite -> Conn Null")
+	}
+	// 回复已接收的指令时附带其追踪编号
+	if gMsg.Trace == "" && gMsg.ID != "" {
+		if trace, found := express.traceIds.Get(gMsg.ID).(string); found {
+			traced := *gMsg
+			traced.Trace = trace
+			gMsg = &traced
+		}
+	}
+	_, err := conn.Write(express.brain.MessageEncrypt(express.brain.EncodeMessage(express.WSCodec(conn), gMsg), express.WSConn(conn).KeyId))
+	return err
+}
+
 //* Websocket广播 */
 func (express *ExpressS) WSBroadcast(callback func(rank int, ip string, neuronId string, conn *websocket.Conn), wsHubs ...model.SyncMapHub) {
 	wsHub := express.hub
@@ -638,11 +968,54 @@ func (express *ExpressS) WSBroadcast(callback func(rank int, ip string, neuronId
 	}
 }
 
+//* 解析EVAL指令的目标[Cmds[0] -> Service, Cmds[1] -> Function,缺失或非字符串则返回false] */
+func (express *ExpressS) EvalTarget(gMsg *model.GMessageS) (string, string, bool) {
+	if gMsg == nil || len(gMsg.Cmds) < 2 {
+		return "", "", false
+	}
+	service, found := gMsg.Cmds[0].(string)
+	if !found || service == "" {
+		return "", "", false
+	}
+	function, found := gMsg.Cmds[1].(string)
+	if !found || function == "" {
+		return "", "", false
+	}
+	return service, function, true
+}
+
+//* 写入CommanderQueue[High及以上写入CommanderPriority,Urgent指令插入队首] */
+func (express *ExpressS) CommanderPush(piece model.CommanderPiece) {
+	// 追踪编号默认为指令编号
+	if piece.GMessage.Trace == "" && express.brain.Const.Trace.Enable {
+		piece.GMessage.Trace = piece.GMessage.ID
+	}
+	express.TraceHop(&piece.GMessage, "Commander", model.TraceQueued, piece.NeuronId)
+	switch {
+	case piece.Priority >= model.PriorityUrgent:
+		express.brain.Container.CommanderPriority.UnShift(piece)
+	case piece.Priority >= model.PriorityHigh:
+		express.brain.Container.CommanderPriority.Push(piece)
+	default:
+		express.brain.Container.CommanderQueue.Push(piece)
+	}
+}
+
 //* 通过CommanderQueue发送命令 */
 /*
 behaviorTreeQ -> map[UUID]*BehaviorTreeS
+neuronId -> NeuronId或路由表达式[见model.RouteS]
 */
 func (express *ExpressS) CommanderEval(neuronId, service, function string, params ...[]byte) string {
+	return express.CommanderEvalAt(neuronId, service, function, model.PriorityNormal, time.Time{}, params...)
+}
+
+//* 按优先级及发送时间通过CommanderQueue发送命令 */
+/*
+priority -> model.PriorityLow | PriorityNormal | PriorityHigh | PriorityUrgent
+notBefore -> 不早于该时间发送[零值则立即发送]
+*/
+func (express *ExpressS) CommanderEvalAt(neuronId, service, function string, priority int, notBefore time.Time, params ...[]byte) string {
 	msgId := express.brain.UUID()
 	gmsg := model.GMessageS{
 		ID:   msgId,
@@ -658,10 +1031,299 @@ func (express *ExpressS) CommanderEval(neuronId, service, function string, param
 		gmsg.Cmds = append(gmsg.Cmds, express.brain.Base64Encoder(v))
 	}
 	// 发送指令
-	express.brain.Container.CommanderQueue.Push(model.CommanderPiece{NeuronId: neuronId, GMessage: gmsg})
+	express.CommanderPush(model.CommanderPiece{NeuronId: neuronId, GMessage: gmsg, Priority: priority, NotBefore: notBefore})
 	return msgId
 }
 
+//* 通过CommanderQueue发送命令并返回调用凭证 */
+/*
+timeout -> 超时毫秒数[<=0则使用Interval.CallTimeout]
+call.Wait() -> 阻塞至REPLY或同ID反向EVAL到达/超时/取消
+*/
+func (express *ExpressS) CommanderCall(neuronId, service, function string, timeout int, params ...[]byte) *model.CallS {
+	if timeout <= 0 {
+		timeout = express.brain.Const.Interval.CallTimeout
+	}
+	msgId := express.brain.UUID()
+	gmsg := model.GMessageS{
+		ID:   msgId,
+		Head: "?",
+		Tag:  "EVAL",
+		Cmds: []interface{}{
+			service,
+			function,
+		},
+	}
+	// 并上参数
+	for _, v := range params {
+		gmsg.Cmds = append(gmsg.Cmds, express.brain.Base64Encoder(v))
+	}
+	// 先登记再发送,避免回复先于登记到达
+	call := new(model.CallS).New(msgId, neuronId, service, function, timeout)
+	express.brain.Container.CommanderCall.Set(msgId, call)
+	// 发送指令
+	express.CommanderPush(model.CommanderPiece{NeuronId: neuronId, GMessage: gmsg})
+	return call
+}
+
+//* 向路由匹配的全部节点发送命令并汇总回复[阻塞] */
+/*
+quorum -> 法定回复数[<=0则等待全部节点,大于匹配节点数则Quorum为false]
+timeout -> 超时毫秒数[<=0则使用Interval.CallTimeout]
+达到法定回复数/全部回复/超时后返回,未回复节点的调用将被取消
+*/
+func (express *ExpressS) CommanderGather(route, service, function string, quorum int, timeout int, params ...[]byte) *model.GatherS {
+	if timeout <= 0 {
+		timeout = express.brain.Const.Interval.CallTimeout
+	}
+	gather := &model.GatherS{
+		Route:   route,
+		Replies: make(map[string]*model.CommanderPiece),
+		Timeout: make([]string, 0),
+		Errors:  make(map[string]model.GatherErrorS),
+	}
+	nodes := express.RouteNodes(route)
+	// 法定回复数大于匹配节点数时不可达成
+	if quorum <= 0 {
+		quorum = len(nodes)
+	}
+	// 每个节点独立调用凭证
+	calls := make(map[string]*model.CallS, len(nodes))
+	doneC := make(chan *model.CallS, len(nodes))
+	for _, v := range nodes {
+		call := express.CommanderCall(v.NeuronId, service, function, timeout, params...)
+		calls[v.NeuronId] = call
+		go func(call *model.CallS) {
+			<-call.Done()
+			doneC <- call
+		}(call)
+	}
+	timer := time.NewTimer(time.Duration(timeout) * time.Millisecond)
+	defer timer.Stop()
+	for finished := 0; finished < len(calls) && len(gather.Replies) < quorum; finished++ {
+		select {
+		case call := <-doneC:
+			switch call.Code {
+			case 100:
+				gather.Replies[call.NeuronId] = call.Reply
+			case 103, 104:
+				// 由下方归入Timeout
+			default:
+				gather.Errors[call.NeuronId] = express.gatherError(call)
+			}
+		case <-timer.C:
+			finished = len(calls)
+		}
+	}
+	gather.Quorum = len(nodes) > 0 && len(gather.Replies) >= quorum
+	// 取消未回复的调用
+	for neuronId, call := range calls {
+		if _, found := gather.Replies[neuronId]; found {
+			continue
+		}
+		if _, found := gather.Errors[neuronId]; found {
+			continue
+		}
+		express.CommanderCallCancel(call.ID)
+		gather.Timeout = append(gather.Timeout, neuronId)
+	}
+	sort.Strings(gather.Timeout)
+	return gather
+}
+
+//* 解析被拒绝调用的错误数据[!ERROR回复的MessageS] */
+func (express *ExpressS) gatherError(call *model.CallS) model.GatherErrorS {
+	result := model.GatherErrorS{Code: call.Code}
+	if call.Reply == nil || len(call.Reply.GMessage.Cmds) == 0 {
+		return result
+	}
+	if msgReply, found := express.brain.JsonDecoder(express.brain.Base64Decoder(fmt.Sprint(call.Reply.GMessage.Cmds[0])), new(model.MessageS)).(*model.MessageS); found {
+		result.Data = msgReply.Data
+	}
+	return result
+}
+
+//* 取消等待中的调用 */
+func (express *ExpressS) CommanderCallCancel(msgId string) bool {
+	call, found := express.brain.Container.CommanderCall.Pop(msgId).(*model.CallS)
+	if !found {
+		return false
+	}
+	return call.Resolve(103, nil)
+}
+
+//* 获取Commander节点注册信息[按NeuronId排序,states为空则返回全部] */
+func (express *ExpressS) Nodes(states ...string) []model.NodeS {
+	hub := express.brain.Container.CommanderNodes
+	nodes := make([]model.NodeS, 0, hub.Len())
+	for _, k := range hub.Key2Slice(true) {
+		node, found := hub.Get(k).(*model.NodeS)
+		if !found {
+			continue
+		}
+		if len(states) > 0 {
+			matched := false
+			for _, v := range states {
+				if v == node.State {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				continue
+			}
+		}
+		nodes = append(nodes, *node)
+	}
+	return nodes
+}
+
+//* 按路由表达式选择存活节点[见model.RouteS,指定service则择一策略跳过该服务繁忙的节点] */
+func (express *ExpressS) RouteNodes(expr string, service ...string) []model.NodeS {
+	route := new(model.RouteS).Parse(expr)
+	nodes := make([]model.NodeS, 0)
+	for _, v := range express.Nodes("alive") {
+		if !route.Match(v) {
+			continue
+		}
+		if len(service) > 0 && route.Strategy != "all" && express.NodeBusy(v, service[0]) {
+			continue
+		}
+		nodes = append(nodes, v)
+	}
+	if len(nodes) == 0 {
+		return nodes
+	}
+	switch route.Strategy {
+	case "rr":
+		counterI, _ := express.routeIndex.GetOrSet(route.Raw, new(uint32))
+		index := atomic.AddUint32(counterI.(*uint32), 1)
+		return nodes[int(index-1)%len(nodes) : int(index-1)%len(nodes)+1]
+	case "random":
+		index := express.brain.RandomInt(len(nodes) - 1)
+		return nodes[index : index+1]
+	case "least":
+		index := 0
+		for k := range nodes {
+			if express.nodeLoadLess(nodes[k], nodes[index]) {
+				index = k
+			}
+		}
+		return nodes[index : index+1]
+	}
+	return nodes
+}
+
+//* 比较节点负载[负载/CPU数,相同则比较队列深度,负载未知(-1或未上报指标)排在最后] */
+func (express *ExpressS) nodeLoadLess(a model.NodeS, b model.NodeS) bool {
+	score := func(node model.NodeS) (bool, float64, int) {
+		load := node.Metric.Load
+		known := load >= 0 && node.Metric.NumCPU > 0
+		if known {
+			load = load / float64(node.Metric.NumCPU)
+		}
+		depth := 0
+		for _, v := range node.Metric.QueueDepth {
+			depth += v
+		}
+		return known, load, depth
+	}
+	aKnown, aLoad, aDepth := score(a)
+	bKnown, bLoad, bDepth := score(b)
+	if aKnown != bKnown {
+		return aKnown
+	}
+	if !aKnown {
+		return aDepth < bDepth
+	}
+	return aLoad < bLoad || (aLoad == bLoad && aDepth < bDepth)
+}
+
+//* 节点的服务是否繁忙[任务额度为0] */
+func (express *ExpressS) NodeBusy(node model.NodeS, service string) bool {
+	credit, found := node.Metric.Credit[service]
+	return found && credit == 0
+}
+
+//* 本节点服务的任务额度[未实现CreditI则不限制] */
+func (express *ExpressS) Credit(service string) int {
+	creditor, found := express.neuron.Services.Get(service).(model.CreditI)
+	if !found {
+		return -1
+	}
+	express.creditMutex.Lock()
+	defer express.creditMutex.Unlock()
+	return express.creditOf(service, creditor)
+}
+
+//* 扣除预占后的额度[需持有creditMutex] */
+func (express *ExpressS) creditOf(service string, creditor model.CreditI) int {
+	credit := creditor.Credit()
+	if credit < 0 {
+		return credit
+	}
+	if credit -= express.creditPending[service]; credit < 0 {
+		return 0
+	}
+	return credit
+}
+
+//* 预占服务额度[Receiver于ACK前调用,额度用尽返回false,未上报额度则不限制] */
+func (express *ExpressS) CreditAcquire(service, msgId string) bool {
+	creditor, found := express.neuron.Services.Get(service).(model.CreditI)
+	if !found {
+		return true
+	}
+	express.creditMutex.Lock()
+	defer express.creditMutex.Unlock()
+	credit := express.creditOf(service, creditor)
+	if credit < 0 {
+		return true
+	}
+	if credit == 0 {
+		return false
+	}
+	express.creditPending[service]++
+	express.creditHolds[msgId] = service
+	return true
+}
+
+//* 释放预占的服务额度[EVAL执行完成或放弃时调用] */
+func (express *ExpressS) CreditRelease(msgId string) {
+	express.creditMutex.Lock()
+	defer express.creditMutex.Unlock()
+	service, found := express.creditHolds[msgId]
+	if !found {
+		return
+	}
+	delete(express.creditHolds, msgId)
+	if express.creditPending[service]--; express.creditPending[service] <= 0 {
+		delete(express.creditPending, service)
+	}
+}
+
+//* 本节点全部服务的任务额度[map[Root]Credit] */
+func (express *ExpressS) Credits() map[string]int {
+	credits := make(map[string]int)
+	express.creditMutex.Lock()
+	defer express.creditMutex.Unlock()
+	express.neuron.Services.Iterator(func(n int, k string, v interface{}) bool {
+		if creditor, found := v.(model.CreditI); found {
+			credits[k] = express.creditOf(k, creditor)
+		}
+		return true
+	})
+	return credits
+}
+
+//* Receiver上报任务额度[额度变化时由服务调用,Commander据此恢复派发] */
+func (express *ExpressS) CreditReport(conn *websocket.Conn) error {
+	if conn == nil {
+		return fmt.Errorf("CreditReport -> Conn Null")
+	}
+	return express.WSWrite(conn, &model.GMessageS{ID: express.brain.Const.NeuronId, Head: "!", Tag: "CREDIT", Cmds: []interface{}{express.brain.Base64Encoder(express.brain.JsonEncoder(express.Credits()))}})
+}
+
 //* Receiver返回命令 */
 func (express *ExpressS) ReceiverEval(conn *websocket.Conn, msgId, service, function string, params ...[]byte) error {
 	gmsg := model.GMessageS{
@@ -678,8 +1340,253 @@ func (express *ExpressS) ReceiverEval(conn *websocket.Conn, msgId, service, func
 		gmsg.Cmds = append(gmsg.Cmds, express.brain.Base64Encoder(v))
 	}
 	// 发送指令
-	_, err := conn.Write(express.brain.SystemEncrypt(express.brain.GenerateMessage(gmsg.Head, gmsg.Tag, gmsg.Cmds, gmsg.ID).Bytes()))
-	return err
+	return express.WSWrite(conn, &gmsg)
+}
+
+//* 校验远程调用[声明/权限/参数] */
+/*
+serviceI -> 服务实例
+caller -> 调用者NeuronId[Receiver端为model.RPCCommander]
+args -> conn, messageId, 参数...
+*/
+func (express *ExpressS) RPCCheck(serviceI interface{}, function string, caller string, args []interface{}) (int, interface{}) {
+	if serviceI == nil {
+		return 207, "Service Not Found"
+	}
+	service, found := serviceI.(model.RPCI)
+	if !found {
+		if express.brain.Const.RPC.Strict {
+			return 208, "Service Not Remotely Callable"
+		}
+		return 100, nil
+	}
+	rpc, found := service.RPC()[function]
+	if !found {
+		return 208, "Function Not Remotely Callable"
+	}
+	// 调用者权限
+	if !express.rpcAllowed(rpc.Allow, caller) {
+		return 208, "Permission Denied -> " + caller
+	}
+	// 参数数量与类型
+	if len(args)-2 != len(rpc.Args) {
+		return 207, fmt.Sprintf("Arguments Count -> %d[%d]", len(args)-2, len(rpc.Args))
+	}
+	for k, v := range rpc.Args {
+		if kind := fmt.Sprintf("%T", args[k+2]); kind != v {
+			return 221, fmt.Sprintf("Arguments[%d] -> %s[%s]", k, kind, v)
+		}
+	}
+	return 100, nil
+}
+
+//* 判断调用者是否在允许列表[空则不限] */
+func (express *ExpressS) rpcAllowed(allow []string, caller string) bool {
+	if len(allow) == 0 {
+		return true
+	}
+	roles := express.NodeRoles(caller)
+	for _, v := range allow {
+		if v == caller {
+			return true
+		}
+		for _, vv := range roles {
+			if v == "role:"+vv {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//* 注册类型化远程调用 */
+/*
+handler -> func(ctx model.RPCContextS, req *RequestS) (int, interface{})
+allow -> 允许的调用者[见model.RPCS.Allow]
+请求参数为Base64(JSON(RequestS)),返回100则结果以!REPLY -> Base64(JSON(data))回复,否则以!ERROR回复
+*/
+func (express *ExpressS) RPCRegister(service, function string, handler interface{}, allow ...string) (int, interface{}) {
+	handlerV := reflect.ValueOf(handler)
+	handlerT := handlerV.Type()
+	if handlerT.Kind() != reflect.Func || handlerT.NumIn() != 2 || handlerT.NumOut() != 2 {
+		return 221, "RPCRegister -> Handler Signature Error"
+	}
+	if handlerT.In(0) != reflect.TypeOf(model.RPCContextS{}) || handlerT.In(1).Kind() != reflect.Ptr || handlerT.In(1).Elem().Kind() != reflect.Struct {
+		return 221, "RPCRegister -> Handler Arguments Error"
+	}
+	if handlerT.Out(0).Kind() != reflect.Int {
+		return 221, "RPCRegister -> Handler Returns Error"
+	}
+	express.rpcHub.Set(service+"."+function, model.RPCHandlerS{
+		Service:  service,
+		Function: function,
+		Allow:    allow,
+		Request:  handlerT.In(1).Elem(),
+		Handler:  handlerV,
+	})
+	return 100, nil
+}
+
+//* 执行已注册的远程调用[未注册返回false] */
+func (express *ExpressS) RPCInvoke(service, function string, caller string, args []interface{}) bool {
+	handler, found := express.rpcHub.Get(service + "." + function).(model.RPCHandlerS)
+	if !found {
+		return false
+	}
+	conn, _ := args[0].(*websocket.Conn)
+	ctx := model.RPCContextS{Conn: conn, MessageId: fmt.Sprint(args[1]), Caller: caller, Service: service, Function: function}
+	code, data := express.rpcCall(handler, ctx, args[2:])
+	if conn == nil {
+		return true
+	}
+	if code != 100 {
+		express.brain.MessageHandler(express.tag, fmt.Sprintf("RPCInvoke -> %s.%s[%s]", service, function, caller), code, data)
+		if err := express.RPCError(conn, ctx.MessageId, service, function, code, data); err != nil {
+			express.brain.MessageHandler(express.tag, "RPCInvoke -> RPCError", 214, err)
+		}
+		return true
+	}
+	if err := express.WSWrite(conn, &model.GMessageS{ID: ctx.MessageId, Head: "!", Tag: "REPLY", Cmds: []interface{}{express.brain.Base64Encoder(express.brain.JsonEncoder(data))}}); err != nil {
+		express.brain.MessageHandler(express.tag, "RPCInvoke -> WSWrite", 214, err)
+	}
+	return true
+}
+
+//* 校验并解码参数后调用 */
+func (express *ExpressS) rpcCall(handler model.RPCHandlerS, ctx model.RPCContextS, params []interface{}) (code int, data interface{}) {
+	if !express.rpcAllowed(handler.Allow, ctx.Caller) {
+		return 208, "Permission Denied -> " + ctx.Caller
+	}
+	if len(params) > 1 {
+		return 207, fmt.Sprintf("Arguments Count -> %d[1]", len(params))
+	}
+	req := reflect.New(handler.Request)
+	if len(params) == 1 {
+		param, found := params[0].(string)
+		if !found {
+			return 221, fmt.Sprintf("Arguments[0] -> %T[string]", params[0])
+		}
+		decoder := json.NewDecoder(bytes.NewReader(express.brain.Base64Decoder(param)))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(req.Interface()); err != nil {
+			return 209, "Arguments[0] -> " + err.Error()
+		}
+	}
+	// 不论RunEnv均捕获异常[远程请求不可导致进程退出]
+	defer func() {
+		if err := recover(); err != nil {
+			express.brain.MessageHandler(express.tag, fmt.Sprintf("rpcCall -> %s.%s[%s]", ctx.Service, ctx.Function, ctx.Caller), 204, err)
+			code, data = 204, fmt.Sprintf("Handler Panic -> %v", err)
+		}
+	}()
+	out := handler.Handler.Call([]reflect.Value{reflect.ValueOf(ctx), req})
+	return int(out[0].Int()), out[1].Interface()
+}
+
+//* 通过Commander发起类型化远程调用[阻塞] */
+/*
+req -> 请求结构体
+resp -> 回复结构体指针,为nil则不解码
+返回100时resp已写入,否则data为错误信息
+*/
+func (express *ExpressS) CommanderRPC(neuronId, service, function string, req interface{}, resp interface{}, timeout int) (int, interface{}) {
+	call := express.CommanderCall(neuronId, service, function, timeout, express.brain.JsonEncoder(req))
+	code, reply := call.Wait()
+	if reply == nil || len(reply.GMessage.Cmds) == 0 {
+		return code, express.brain.Const.ErrorCode[code]
+	}
+	body := express.brain.Base64Decoder(fmt.Sprint(reply.GMessage.Cmds[0]))
+	if code != 100 {
+		msgReply := new(model.MessageS)
+		if err := json.Unmarshal(body, msgReply); err != nil {
+			return code, string(body)
+		}
+		return code, msgReply.Data
+	}
+	if resp != nil {
+		if err := json.Unmarshal(body, resp); err != nil {
+			return 209, err
+		}
+	}
+	return 100, resp
+}
+
+//* 获取节点角色[Const.RPC.Roles,及通过握手认证节点的标签role] */
+func (express *ExpressS) NodeRoles(neuronId string) []string {
+	roles := append([]string{}, express.brain.Const.RPC.Roles[neuronId]...)
+	if node, found := express.brain.Container.CommanderNodes.Get(neuronId).(*model.NodeS); found && node.Verified {
+		if role, found := node.Labels["role"]; found {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+//* 回复远程调用错误[!ERROR -> Base64(MessageS)] */
+func (express *ExpressS) RPCError(conn *websocket.Conn, msgId, service, function string, code int, data interface{}) error {
+	msgReply := model.MessageS{
+		Code:    code,
+		Message: express.brain.Const.ErrorCode[code],
+		Data: map[string]interface{}{
+			"Service":  service,
+			"Function": function,
+			"Reason":   data,
+		},
+	}
+	return express.WSWrite(conn, &model.GMessageS{ID: msgId, Head: "!", Tag: "ERROR", Cmds: []interface{}{express.brain.Base64Encoder(express.brain.JsonEncoder(msgReply))}})
+}
+
+//* 按重连策略维持客户端连接[阻塞至stopC关闭或超出最大失败次数] */
+/*
+client -> express.WSClient | express.TCPClient
+mTrigger -> 转发client的Open/Message/Error/Close事件,另有State事件[data -> model.ConnEventS]
+*/
+func (express *ExpressS) Reconnect(policy *model.ReconnectS, client func(u string, mTrigger trigger.Trigger, heartIntervals ...int), mTrigger trigger.Trigger, stopC chan bool, heartIntervals ...int) {
+	if express.brain.CheckIsNull(mTrigger) {
+		express.brain.MessageHandler(express.tag, "Reconnect", 220, "mTrigger -> Null")
+		return
+	}
+	state := func(state string, host string, delay time.Duration) {
+		mTrigger.FireBackground("State", 100, model.ConnEventS{State: state, Host: host, Attempt: policy.Attempt(), Delay: int(delay / time.Millisecond)})
+	}
+	for {
+		host := policy.Host()
+		state(model.ConnConnecting, host, 0)
+		// 单次连接事件转发
+		mClient := trigger.New()
+		mClient.On("Open", func(code int, data interface{}) {
+			policy.Reset()
+			state(model.ConnConnected, host, 0)
+			mTrigger.Fire("Open", code, data)
+		})
+		for _, event := range []string{"Message", "Error", "Close"} {
+			event := event
+			mClient.On(event, func(code int, data interface{}) {
+				mTrigger.Fire(event, code, data)
+			})
+		}
+		client(host, mClient, heartIntervals...)
+		select {
+		case <-stopC:
+			state(model.ConnStopped, host, 0)
+			return
+		default:
+		}
+		delay, retry := policy.Next()
+		if !retry {
+			state(model.ConnFailed, host, 0)
+			return
+		}
+		state(model.ConnBackoff, policy.Host(), delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-stopC:
+			timer.Stop()
+			state(model.ConnStopped, host, 0)
+			return
+		case <-timer.C:
+		}
+	}
 }
 
 //* Websocket客户端 */
@@ -737,7 +1644,8 @@ func (express *ExpressS) WSClient(u string, mTrigger trigger.Trigger, heartInter
 					break
 				}
 			}
-			msgC <- msgBuf.Bytes()
+			// Message事件异步处理,复制后再复用缓冲区
+			msgC <- append([]byte(nil), msgBuf.Bytes()...)
 			msgBuf.Reset()
 		}, func(err interface{}) {
 			if err == nil {
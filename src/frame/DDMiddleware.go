@@ -0,0 +1,203 @@
+/**
+===========================================================================
+ * 中间件管道
+ * Express middleware pipeline
+ * 执行顺序 -> 全局[Use] -> 服务根路径[UseService/RouterS.Use,子路径叠加于其后] -> 路由[RouterS.Handle]
+ * 中间件不调用next则短路,后续中间件及接口均不执行
+ * 内置 -> MiddlewareLog / MiddlewareHeader / MiddlewareCORS / MiddlewareRequestId
+===========================================================================
+*/
+package frame
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"model"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//* ================================ DEFINE ================================ */
+
+//* 中间件[调用next进入下一层] */
+type MiddlewareF func(ctx *ContextS, next func())
+
+//* 请求上下文 */
+type ContextS struct {
+	Res http.ResponseWriter
+	// 附带本上下文的请求[中间件可替换]
+	Req       *http.Request
+	RequestId string
+	Start     time.Time
+
+	// 原请求[接口处理函数持有]
+	origin *http.Request
+	mutex  sync.RWMutex
+	values map[string]interface{}
+}
+
+//* 请求上下文键 */
+type contextKey struct{}
+
+//* 附带请求的ResponseWriter[供CodeResponse内容协商] */
+type responseS struct {
+	http.ResponseWriter
+	req *http.Request
+}
+
+//* 接管连接[Websocket] */
+func (res *responseS) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, found := res.ResponseWriter.(http.Hijacker)
+	if !found {
+		return nil, nil, fmt.Errorf("Hijack -> Not Supported")
+	}
+	return hijacker.Hijack()
+}
+
+//* 刷新缓冲 */
+func (res *responseS) Flush() {
+	if flusher, found := res.ResponseWriter.(http.Flusher); found {
+		flusher.Flush()
+	}
+}
+
+//* 写入上下文数据 */
+func (ctx *ContextS) Set(key string, value interface{}) {
+	ctx.mutex.Lock()
+	defer ctx.mutex.Unlock()
+	ctx.values[key] = value
+}
+
+//* 读取上下文数据 */
+func (ctx *ContextS) Get(key string) interface{} {
+	ctx.mutex.RLock()
+	defer ctx.mutex.RUnlock()
+	return ctx.values[key]
+}
+
+//* ================================ PRIVATE ================================ */
+
+//* 依次执行中间件 */
+func (express *ExpressS) middlewareRun(ctx *ContextS, chain []MiddlewareF, final func()) {
+	var run func(index int)
+	run = func(index int) {
+		if index == len(chain) {
+			final()
+			return
+		}
+		chain[index](ctx, func() {
+			run(index + 1)
+		})
+	}
+	run(0)
+}
+
+//* 匹配请求路径的服务中间件[按根路径由短至长叠加] */
+func (express *ExpressS) middlewareService(path string) []MiddlewareF {
+	roots := make([]string, 0)
+	for _, k := range express.middlewareHub.Key2Slice() {
+		if path == k || strings.HasPrefix(path, k+"/") {
+			roots = append(roots, k)
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool {
+		return len(roots[i]) < len(roots[j])
+	})
+	chain := make([]MiddlewareF, 0)
+	for _, v := range roots {
+		middlewares, _ := express.middlewareHub.Get(v).([]MiddlewareF)
+		chain = append(chain, middlewares...)
+	}
+	return chain
+}
+
+//* 构建上下文并执行全局及服务中间件 */
+func (express *ExpressS) middleware(res http.ResponseWriter, req *http.Request, next func(ctx *ContextS)) {
+	ctx := &ContextS{
+		Res:    res,
+		Start:  time.Now(),
+		origin: req,
+		values: make(map[string]interface{}),
+	}
+	ctx.Req = req.WithContext(context.WithValue(req.Context(), contextKey{}, ctx))
+	express.middlewareMutex.RLock()
+	chain := append([]MiddlewareF{}, express.middlewares...)
+	express.middlewareMutex.RUnlock()
+	chain = append(chain, express.middlewareService(req.URL.Path)...)
+	express.middlewareRun(ctx, chain, func() {
+		next(ctx)
+	})
+}
+
+//* ================================ PUBLIC ================================ */
+
+//* 注册全局中间件 */
+func (express *ExpressS) Use(middlewares ...MiddlewareF) {
+	express.middlewareMutex.Lock()
+	defer express.middlewareMutex.Unlock()
+	express.middlewares = append(express.middlewares, middlewares...)
+}
+
+//* 注册服务根路径中间件[root可为子路径,如/System/Config] */
+func (express *ExpressS) UseService(root string, middlewares ...MiddlewareF) {
+	express.middlewareMutex.Lock()
+	defer express.middlewareMutex.Unlock()
+	chain, _ := express.middlewareHub.Get(root).([]MiddlewareF)
+	express.middlewareHub.Set(root, append(append([]MiddlewareF{}, chain...), middlewares...))
+}
+
+//* 包装服务入口[ResponseWriter附带请求,供CodeResponse内容协商] */
+func (express *ExpressS) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		next.ServeHTTP(&responseS{ResponseWriter: res, req: req}, req)
+	})
+}
+
+//* 获取请求上下文[未经中间件则返回nil] */
+func (express *ExpressS) Context(req *http.Request) *ContextS {
+	ctx, _ := req.Context().Value(contextKey{}).(*ContextS)
+	return ctx
+}
+
+//* 内置 -> 访问日志 */
+func (express *ExpressS) MiddlewareLog() MiddlewareF {
+	return func(ctx *ContextS, next func()) {
+		express.brain.LogGenerater(model.LogTrace, express.tag, "Middleware", fmt.Sprintf("[Visitor] => %s [Resource] => %s %s", ctx.Req.RemoteAddr, ctx.Req.Method, ctx.Req.URL))
+		next()
+	}
+}
+
+//* 内置 -> X-Powered-By */
+func (express *ExpressS) MiddlewareHeader() MiddlewareF {
+	return func(ctx *ContextS, next func()) {
+		ctx.Res.Header().Set("X-Powered-By", express.brain.Const.HTTPServer.XPoweredBy)
+		next()
+	}
+}
+
+//* 内置 -> 跨域[HTTPServer.ACAO] */
+func (express *ExpressS) MiddlewareCORS() MiddlewareF {
+	return func(ctx *ContextS, next func()) {
+		if express.brain.Const.HTTPServer.ACAO {
+			ctx.Res.Header().Set("Access-Control-Allow-Origin", "*")
+		}
+		next()
+	}
+}
+
+//* 内置 -> 请求编号[沿用X-Request-Id,否则生成] */
+func (express *ExpressS) MiddlewareRequestId() MiddlewareF {
+	return func(ctx *ContextS, next func()) {
+		ctx.RequestId = ctx.Req.Header.Get("X-Request-Id")
+		if ctx.RequestId == "" {
+			ctx.RequestId = express.brain.UUID()
+		}
+		ctx.Res.Header().Set("X-Request-Id", ctx.RequestId)
+		next()
+	}
+}
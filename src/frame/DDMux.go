@@ -0,0 +1,198 @@
+/**
+===========================================================================
+ * 连接多路复用 -> 在已认证的Websocket上建立MuxS会话[负载以二进制帧传输]
+ * 每个流首行为目标地址,服务端拨号后双向转发
+ * 客户端 -> 目标地址 + '\n'
+ * 服务端 -> "+\n" 已连接 | "-" + 原因 + "\n" 拒绝并关闭流
+ * 目标地址须位于Proxy.TunnelAllow中
+===========================================================================
+*/
+package frame
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"model"
+	"modules/websocket"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+//* ================================ DEFINE ================================ */
+
+//* 流首行字节数上限 */
+const muxLineLen = 512
+
+//* ================================ PRIVATE ================================ */
+
+//* 读取流首行[逐字节读取,不缓冲首行之后的数据] */
+func (express *ExpressS) muxReadLine(stream net.Conn) (string, error) {
+	deadline := time.Now().Add(time.Duration(express.brain.Const.TCPParam.Interval) * time.Millisecond)
+	stream.SetReadDeadline(deadline)
+	defer stream.SetReadDeadline(time.Time{})
+	line := make([]byte, 0, 64)
+	b := make([]byte, 1)
+	for len(line) < muxLineLen {
+		if _, err := stream.Read(b); err != nil {
+			return "", err
+		}
+		if b[0] == '\n' {
+			return string(line), nil
+		}
+		line = append(line, b[0])
+	}
+	return "", fmt.Errorf("muxReadLine -> Line Too Long")
+}
+
+//* 转发流至首行目标地址 */
+func (express *ExpressS) muxForward(stream *model.MuxStreamS) {
+	target, err := express.muxReadLine(stream)
+	if err != nil {
+		express.brain.MessageHandler(express.tag, "muxForward -> Read", 216, err)
+		stream.Reset()
+		return
+	}
+	reject := func(reason string) {
+		stream.Write([]byte("-" + strings.Replace(reason, "\n", " ", -1) + "\n"))
+		stream.Close()
+	}
+	if !express.tunnelAllowed(target) {
+		express.brain.MessageHandler(express.tag, "muxForward -> Not Allowed", 208, target)
+		reject("Target Not Allowed")
+		return
+	}
+	conn, err := net.DialTimeout("tcp", target, time.Duration(express.brain.Const.TCPParam.Interval)*time.Millisecond)
+	if err != nil {
+		express.brain.MessageHandler(express.tag, "muxForward -> Dial", 210, err)
+		reject(err.Error())
+		return
+	}
+	if _, err := stream.Write([]byte("+\n")); err != nil {
+		conn.Close()
+		stream.Reset()
+		return
+	}
+	express.muxPipe(stream, conn)
+}
+
+//* 双向转发[单向结束则半关闭,异常则终止双向] */
+func (express *ExpressS) muxPipe(stream *model.MuxStreamS, conn net.Conn) {
+	doneC := make(chan bool, 2)
+	go func() {
+		_, err := io.Copy(conn, stream)
+		if tcpConn, found := conn.(*net.TCPConn); found && err == nil {
+			tcpConn.CloseWrite()
+		} else {
+			conn.Close()
+		}
+		doneC <- true
+	}()
+	go func() {
+		_, err := io.Copy(stream, conn)
+		if err == nil {
+			stream.Close()
+		} else {
+			stream.Reset()
+		}
+		doneC <- true
+	}()
+	<-doneC
+	<-doneC
+	conn.Close()
+	stream.Close()
+}
+
+//* ================================ PUBLIC ================================ */
+
+//* 在连接上建立多路复用会话[conn -> *websocket.Conn | net.Conn,isClient -> 发起连接的一端] */
+func (express *ExpressS) Mux(conn net.Conn, isClient bool) *model.MuxS {
+	mux := new(model.MuxS).New(conn, isClient, express.brain.Const.Mux.Window, express.brain.Const.Mux.Backlog)
+	go express.brain.SafeFunction(func() {
+		<-mux.Done()
+		if err := mux.Err(); err != nil && err != model.ErrMuxClosed && err != io.EOF {
+			express.brain.MessageHandler(express.tag, "Mux", 216, err)
+		}
+	})
+	return mux
+}
+
+//* 在Websocket上建立多路复用会话[负载改为二进制帧] */
+func (express *ExpressS) WSMux(ws *websocket.Conn, isClient bool) *model.MuxS {
+	ws.PayloadType = websocket.BinaryFrame
+	return express.Mux(ws, isClient)
+}
+
+//* 接受会话中的流并转发至首行目标地址[阻塞至会话结束] */
+func (express *ExpressS) MuxForward(session *model.MuxS) {
+	for {
+		stream, err := session.AcceptStream()
+		if err != nil {
+			return
+		}
+		go express.brain.SafeFunction(func() {
+			express.muxForward(stream)
+		})
+	}
+}
+
+//* 连接多路复用接口并建立客户端会话[header -> 认证头,见DDAuth] */
+func (express *ExpressS) MuxDial(u string, header http.Header) (*model.MuxS, error) {
+	uParsed, err := url.Parse(u)
+	if err != nil {
+		return nil, err
+	}
+	config, err := websocket.NewConfig(u, "http://"+uParsed.Host)
+	if err != nil {
+		return nil, err
+	}
+	config.Header = header
+	config.Dialer = &net.Dialer{
+		Timeout: time.Duration(express.brain.Const.TCPParam.Interval) * time.Millisecond,
+	}
+	config.TlsConfig = &tls.Config{
+		InsecureSkipVerify: true,
+	}
+	conn, err := websocket.DialConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return express.WSMux(conn, true), nil
+}
+
+//* 在会话中打开流并连接至目标地址 */
+func (express *ExpressS) MuxOpen(session *model.MuxS, target string) (net.Conn, error) {
+	stream, err := session.Open()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stream.Write([]byte(target + "\n")); err != nil {
+		stream.Reset()
+		return nil, err
+	}
+	status, err := express.muxReadLine(stream)
+	if err != nil {
+		stream.Reset()
+		return nil, err
+	}
+	if status != "+" {
+		stream.Close()
+		return nil, fmt.Errorf("MuxOpen -> %s", strings.TrimPrefix(status, "-"))
+	}
+	return stream, nil
+}
+
+//* 多路复用Websocket处理器[须经认证的服务路径,hub -> 会话容器] */
+func (express *ExpressS) MuxHandler(hub *model.SyncMapHub) websocket.Handler {
+	return func(ws *websocket.Conn, wsI model.WebsocketI) {
+		session := express.WSMux(ws, false)
+		key := ws.Request().RemoteAddr
+		hub.Set(key, session)
+		defer hub.Del(key)
+		defer session.Close()
+		express.MuxForward(session)
+	}
+}
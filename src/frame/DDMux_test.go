@@ -0,0 +1,73 @@
+package frame
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+)
+
+//* 回环echo服务 */
+func muxEcho(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		io.Copy(conn, conn)
+		conn.Close()
+	}()
+	return listener.Addr().String()
+}
+
+func TestMuxOpen(t *testing.T) {
+	echo := muxEcho(t)
+	express := &ExpressS{tag: "Express", brain: testBrain()}
+	express.brain.Const.Proxy.TunnelAllow = []string{echo, "127.0.0.1:1"}
+	client, server := net.Pipe()
+	clientSession, serverSession := express.Mux(client, true), express.Mux(server, false)
+	defer clientSession.Close()
+	defer serverSession.Close()
+	go express.MuxForward(serverSession)
+	cases := []struct {
+		name   string
+		target string
+		err    string
+	}{
+		{"allowed", echo, ""},
+		{"notAllowed", "127.0.0.1:2", "Target Not Allowed"},
+		{"dialFailed", "127.0.0.1:1", "refused"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			conn, err := express.MuxOpen(clientSession, c.target)
+			if c.err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("MuxOpen = %v, want %v", err, c.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := conn.Write([]byte("hello")); err != nil {
+				t.Fatal(err)
+			}
+			// 半关闭后仍可读取echo剩余数据
+			conn.Close()
+			data, err := ioutil.ReadAll(conn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != "hello" {
+				t.Fatalf("echo = %q, want hello", data)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@
 package frame
 
 import (
+	"fmt"
 	"modules/redigo/redis"
 	"strconv"
 	"time"
@@ -49,6 +50,182 @@ func (mRedis *RedisS) newPool() *redis.Pool {
 	}
 }
 
+//* 值一致时续约 */
+var redisRenewScript = redis.NewScript(1, `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("PEXPIRE", KEYS[1], ARGV[2]) else return 0 end`)
+
+//* 值一致时删除 */
+var redisReleaseScript = redis.NewScript(1, `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) else return 0 end`)
+
+//* ================================ STORE ================================ */
+
+//* 抢占租约 */
+func (mRedis *RedisS) Acquire(key, value string, ttl int) (bool, error) {
+	conn := mRedis.Pool.Get()
+	defer conn.Close()
+	_, err := redis.String(conn.Do("SET", key, value, "NX", "PX", ttl))
+	if err == redis.ErrNil {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+//* 续约 */
+func (mRedis *RedisS) Renew(key, value string, ttl int) (bool, error) {
+	conn := mRedis.Pool.Get()
+	defer conn.Close()
+	n, err := redis.Int(redisRenewScript.Do(conn, key, value, ttl))
+	return n == 1, err
+}
+
+//* 释放租约 */
+func (mRedis *RedisS) Release(key, value string) error {
+	conn := mRedis.Pool.Get()
+	defer conn.Close()
+	_, err := redisReleaseScript.Do(conn, key, value)
+	return err
+}
+
+//* 读取租约值 */
+func (mRedis *RedisS) Get(key string) (string, error) {
+	conn := mRedis.Pool.Get()
+	defer conn.Close()
+	value, err := redis.String(conn.Do("GET", key))
+	if err == redis.ErrNil {
+		return "", nil
+	}
+	return value, err
+}
+
+//* 列表写入 */
+func (mRedis *RedisS) ListPush(key string, value []byte, head bool) error {
+	conn := mRedis.Pool.Get()
+	defer conn.Close()
+	command := "RPUSH"
+	if head {
+		command = "LPUSH"
+	}
+	_, err := conn.Do(command, key, value)
+	return err
+}
+
+//* 列表取出 */
+func (mRedis *RedisS) ListPop(key string, head bool) ([]byte, error) {
+	conn := mRedis.Pool.Get()
+	defer conn.Close()
+	command := "RPOP"
+	if head {
+		command = "LPOP"
+	}
+	value, err := redis.Bytes(conn.Do(command, key))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	return value, err
+}
+
+//* 列表读取 */
+func (mRedis *RedisS) ListIndex(key string, index int) ([]byte, error) {
+	conn := mRedis.Pool.Get()
+	defer conn.Close()
+	value, err := redis.Bytes(conn.Do("LINDEX", key, index))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	return value, err
+}
+
+//* 列表长度 */
+func (mRedis *RedisS) ListLen(key string) (int, error) {
+	conn := mRedis.Pool.Get()
+	defer conn.Close()
+	return redis.Int(conn.Do("LLEN", key))
+}
+
+//* 列表全部元素 */
+func (mRedis *RedisS) ListRange(key string) ([][]byte, error) {
+	conn := mRedis.Pool.Get()
+	defer conn.Close()
+	return redis.ByteSlices(conn.Do("LRANGE", key, 0, -1))
+}
+
+//* 散列写入 */
+func (mRedis *RedisS) HashSet(key, field string, value []byte) error {
+	conn := mRedis.Pool.Get()
+	defer conn.Close()
+	_, err := conn.Do("HSET", key, field, value)
+	return err
+}
+
+//* 散列读取 */
+func (mRedis *RedisS) HashGet(key, field string) ([]byte, error) {
+	conn := mRedis.Pool.Get()
+	defer conn.Close()
+	value, err := redis.Bytes(conn.Do("HGET", key, field))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	return value, err
+}
+
+//* 散列删除 */
+func (mRedis *RedisS) HashDel(key, field string) error {
+	conn := mRedis.Pool.Get()
+	defer conn.Close()
+	_, err := conn.Do("HDEL", key, field)
+	return err
+}
+
+//* 散列长度 */
+func (mRedis *RedisS) HashLen(key string) (int, error) {
+	conn := mRedis.Pool.Get()
+	defer conn.Close()
+	return redis.Int(conn.Do("HLEN", key))
+}
+
+//* 散列全部元素 */
+func (mRedis *RedisS) HashAll(key string) (map[string][]byte, error) {
+	conn := mRedis.Pool.Get()
+	defer conn.Close()
+	values, err := redis.StringMap(conn.Do("HGETALL", key))
+	if err != nil {
+		return nil, err
+	}
+	hash := make(map[string][]byte, len(values))
+	for k, v := range values {
+		hash[k] = []byte(v)
+	}
+	return hash, nil
+}
+
+//* 按前缀列出键[SCAN] */
+func (mRedis *RedisS) Keys(prefix string) ([]string, error) {
+	conn := mRedis.Pool.Get()
+	defer conn.Close()
+	keys := make([]string, 0)
+	cursor := 0
+	for {
+		values, err := redis.Values(conn.Do("SCAN", cursor, "MATCH", prefix+"*", "COUNT", 100))
+		if err != nil {
+			return keys, err
+		}
+		if len(values) != 2 {
+			return keys, fmt.Errorf("SCAN -> Reply Error")
+		}
+		cursor, err = redis.Int(values[0], nil)
+		if err != nil {
+			return keys, err
+		}
+		batch, err := redis.Strings(values[1], nil)
+		if err != nil {
+			return keys, err
+		}
+		keys = append(keys, batch...)
+		if cursor == 0 {
+			return keys, nil
+		}
+	}
+}
+
 //* ================================ PUBLIC ================================ */
 
 //* 构造本体 */
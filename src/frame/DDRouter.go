@@ -0,0 +1,210 @@
+/**
+===========================================================================
+ * 服务路由器
+ * Express service router
+ * 每个服务根路径一个路由组,挂载于mux的root+"/"子树
+ * pattern -> /Nodes | /Nodes/{id} | /Files/{path...}
+ * 路径匹配而方法不匹配返回405,均不匹配返回404
+ * 经ConstructInterface进入中间件管道[全局 -> 服务 -> 路由]
+===========================================================================
+*/
+package frame
+
+import (
+	"context"
+	"model"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//* ================================ DEFINE ================================ */
+
+//* 路由组 */
+type RouterS struct {
+	express *ExpressS
+	root    string
+	service model.ExpressI
+	mutex   sync.RWMutex
+	routes  []*routeS
+}
+
+//* 路由 */
+type routeS struct {
+	method      string
+	pattern     string
+	segments    []string
+	handler     http.HandlerFunc
+	middlewares []MiddlewareF
+}
+
+//* 路径参数上下文键 */
+type routeParamKey struct{}
+
+//* ================================ PRIVATE ================================ */
+
+//* 拆分路径[忽略首尾斜杠] */
+func routeSplit(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return []string{}
+	}
+	return strings.Split(path, "/")
+}
+
+//* 匹配路径并提取参数 */
+func (route *routeS) match(segments []string) (map[string]string, bool) {
+	params := make(map[string]string)
+	for k, v := range route.segments {
+		if !strings.HasPrefix(v, "{") || !strings.HasSuffix(v, "}") {
+			if k >= len(segments) || segments[k] != v {
+				return nil, false
+			}
+			continue
+		}
+		name := v[1 : len(v)-1]
+		// 通配剩余路径
+		if strings.HasSuffix(name, "...") {
+			params[strings.TrimSuffix(name, "...")] = strings.Join(segments[k:], "/")
+			return params, true
+		}
+		if k >= len(segments) || segments[k] == "" {
+			return nil, false
+		}
+		params[name] = segments[k]
+	}
+	return params, len(segments) == len(route.segments)
+}
+
+//* 分发请求 */
+func (router *RouterS) dispatch(ctx *ContextS) {
+	res, req := ctx.Res, ctx.Req
+	segments := routeSplit(strings.TrimPrefix(req.URL.Path, router.root))
+	method := req.Method
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+	allowed := make([]string, 0)
+	router.mutex.RLock()
+	routes := router.routes
+	router.mutex.RUnlock()
+	for _, v := range routes {
+		params, found := v.match(segments)
+		if !found {
+			continue
+		}
+		if v.method != "" && v.method != method {
+			allowed = append(allowed, v.method)
+			continue
+		}
+		ctx.Req = req.WithContext(context.WithValue(req.Context(), routeParamKey{}, params))
+		router.express.middlewareRun(ctx, v.middlewares, func() {
+			v.handler(ctx.Res, ctx.Req)
+		})
+		return
+	}
+	if len(allowed) > 0 {
+		sort.Strings(allowed)
+		res.Header().Set("Allow", strings.Join(allowed, ", "))
+		if req.Method == http.MethodOptions {
+			res.WriteHeader(http.StatusNoContent)
+			return
+		}
+		router.express.StatusResponse(res, http.StatusMethodNotAllowed, 207, "Method Not Allowed -> "+req.Method, "RouterS")
+		return
+	}
+	router.express.StatusResponse(res, http.StatusNotFound, 207, "Not Found -> "+req.URL.Path, "RouterS")
+}
+
+//* ================================ PUBLIC ================================ */
+
+//* 注册路由[method为空则匹配全部方法,middlewares仅作用于该路由] */
+func (router *RouterS) Handle(method, pattern string, handler http.HandlerFunc, middlewares ...MiddlewareF) *RouterS {
+	router.mutex.Lock()
+	defer router.mutex.Unlock()
+	router.routes = append(router.routes, &routeS{
+		method:      strings.ToUpper(method),
+		pattern:     pattern,
+		segments:    routeSplit(pattern),
+		handler:     handler,
+		middlewares: middlewares,
+	})
+	return router
+}
+
+//* 注册服务根路径中间件[同样作用于该服务直接注册于mux的接口] */
+func (router *RouterS) Use(middlewares ...MiddlewareF) *RouterS {
+	router.express.UseService(router.root, middlewares...)
+	return router
+}
+
+//* 注册GET路由 */
+func (router *RouterS) GET(pattern string, handler http.HandlerFunc, middlewares ...MiddlewareF) *RouterS {
+	return router.Handle(http.MethodGet, pattern, handler, middlewares...)
+}
+
+//* 注册POST路由 */
+func (router *RouterS) POST(pattern string, handler http.HandlerFunc, middlewares ...MiddlewareF) *RouterS {
+	return router.Handle(http.MethodPost, pattern, handler, middlewares...)
+}
+
+//* 注册PUT路由 */
+func (router *RouterS) PUT(pattern string, handler http.HandlerFunc, middlewares ...MiddlewareF) *RouterS {
+	return router.Handle(http.MethodPut, pattern, handler, middlewares...)
+}
+
+//* 注册DELETE路由 */
+func (router *RouterS) DELETE(pattern string, handler http.HandlerFunc, middlewares ...MiddlewareF) *RouterS {
+	return router.Handle(http.MethodDelete, pattern, handler, middlewares...)
+}
+
+//* 已注册的路由[METHOD pattern] */
+func (router *RouterS) Routes() []string {
+	router.mutex.RLock()
+	defer router.mutex.RUnlock()
+	result := make([]string, 0, len(router.routes))
+	for _, v := range router.routes {
+		method := v.method
+		if method == "" {
+			method = "*"
+		}
+		result = append(result, method+" "+router.root+v.pattern)
+	}
+	return result
+}
+
+//* 实现http.Handler[经ConstructInterface进入Middleware] */
+func (router *RouterS) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	router.express.constructInterface(res, req, router.service.IsStarted(), func(ctx *ContextS) {
+		router.dispatch(ctx)
+	}, func(err interface{}) {
+		router.express.CodeResponse(res, 204, err, "RouterS[ConstructInterface]")
+	})
+}
+
+//* 创建服务路由组[挂载于root+"/"子树,已单独注册的精确路径优先] */
+func (express *ExpressS) Group(mux *http.ServeMux, root string, service model.ExpressI) *RouterS {
+	router := &RouterS{
+		express: express,
+		root:    root,
+		service: service,
+		routes:  make([]*routeS, 0),
+	}
+	mux.Handle(root+"/", router)
+	return router
+}
+
+//* 获取路径参数 */
+func (express *ExpressS) Params(req *http.Request) map[string]string {
+	params, found := req.Context().Value(routeParamKey{}).(map[string]string)
+	if !found {
+		return map[string]string{}
+	}
+	return params
+}
+
+//* 获取单个路径参数 */
+func (express *ExpressS) Param(req *http.Request, name string) string {
+	return express.Params(req)[name]
+}
@@ -0,0 +1,245 @@
+/**
+===========================================================================
+ * 指令追踪
+ * GMessage tracing
+ * Commander -> queued / sent / acked / replied / dead
+ * Receiver -> received / executed[EVAL执行完成后,经!TRACE上报至Commander]
+ * 记录缓冲写入本地Json行日志,最近的追踪保留于内存
+===========================================================================
+*/
+package frame
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"model"
+	"modules/websocket"
+	"os"
+	"path"
+	"sort"
+	"time"
+)
+
+//* ================================ DEFINE ================================ */
+
+const (
+	// 追踪日志写缓冲
+	traceBufferSize = 64 << 10
+	// 缓冲刷新延迟
+	traceFlushDelay = time.Second
+)
+
+//* ================================ PRIVATE ================================ */
+
+//* 刷新追踪日志缓冲[需持有traceMutex] */
+func (express *ExpressS) traceFlush() {
+	express.traceFlushing = false
+	if express.traceWriter == nil {
+		return
+	}
+	if err := express.traceWriter.Flush(); err != nil {
+		express.brain.MessageHandler(express.tag, "traceFlush -> Flush", 216, err)
+	}
+}
+
+//* 写入追踪日志[缓冲写入,超出MaxSize则轮转] */
+func (express *ExpressS) traceWrite(hop model.TraceHopS) {
+	param := express.brain.Const.Trace
+	filePath := express.brain.PathAbs(param.Path)
+	line, err := json.Marshal(hop)
+	if err != nil {
+		return
+	}
+	express.traceMutex.Lock()
+	defer express.traceMutex.Unlock()
+	if express.traceFile == nil {
+		if code, data := express.brain.PathCreate(path.Dir(filePath)); code != 100 {
+			express.brain.MessageHandler(express.tag, "traceWrite -> PathCreate", code, data)
+			return
+		}
+		express.traceFile, err = os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, os.FileMode(express.brain.Const.File.Chmod))
+		if err != nil {
+			express.brain.MessageHandler(express.tag, "traceWrite -> OpenFile", 216, err)
+			return
+		}
+		express.traceSize = 0
+		if info, err := express.traceFile.Stat(); err == nil {
+			express.traceSize = info.Size()
+		}
+		express.traceWriter = bufio.NewWriterSize(express.traceFile, traceBufferSize)
+	}
+	n, err := express.traceWriter.Write(append(line, '\n'))
+	if err != nil {
+		express.brain.MessageHandler(express.tag, "traceWrite -> Write", 216, err)
+		return
+	}
+	express.traceSize += int64(n)
+	if param.MaxSize > 0 && express.traceSize > param.MaxSize {
+		express.traceFlush()
+		express.traceFile.Close()
+		express.traceFile = nil
+		express.traceWriter = nil
+		os.Rename(filePath, filePath+".1")
+		return
+	}
+	// 延迟刷新至文件
+	if !express.traceFlushing {
+		express.traceFlushing = true
+		time.AfterFunc(traceFlushDelay, func() {
+			express.traceMutex.Lock()
+			defer express.traceMutex.Unlock()
+			express.traceFlush()
+		})
+	}
+}
+
+//* 写入内存索引 */
+func (express *ExpressS) traceMemory(hop model.TraceHopS) {
+	express.traceMutex.Lock()
+	defer express.traceMutex.Unlock()
+	hopsI := express.traceHub.Get(hop.Trace)
+	if hopsI == nil {
+		express.traceQ.Push(hop.Trace)
+		for express.traceQ.Len() > express.brain.Const.Trace.MemoryLen {
+			if old, found := express.traceQ.Shift().(string); found {
+				express.traceHub.Del(old)
+			}
+		}
+	}
+	hops, _ := hopsI.([]model.TraceHopS)
+	express.traceHub.Set(hop.Trace, append(hops, hop))
+}
+
+//* 从日志文件查找追踪 */
+func (express *ExpressS) traceScan(trace string) []model.TraceHopS {
+	filePath := express.brain.PathAbs(express.brain.Const.Trace.Path)
+	// 先刷新缓冲
+	express.traceMutex.Lock()
+	express.traceFlush()
+	express.traceMutex.Unlock()
+	hops := make([]model.TraceHopS, 0)
+	for _, v := range []string{filePath + ".1", filePath} {
+		file, err := os.Open(v)
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var hop model.TraceHopS
+			if json.Unmarshal(scanner.Bytes(), &hop) == nil && (hop.Trace == trace || hop.MessageId == trace) {
+				hops = append(hops, hop)
+			}
+		}
+		file.Close()
+	}
+	return hops
+}
+
+//* ================================ PUBLIC ================================ */
+
+//* 记录追踪阶段 */
+func (express *ExpressS) TraceHop(gMsg *model.GMessageS, neuronId string, stage string, detail ...interface{}) {
+	if !express.brain.Const.Trace.Enable || gMsg == nil || gMsg.Trace == "" {
+		return
+	}
+	hop := model.TraceHopS{
+		Trace:     gMsg.Trace,
+		MessageId: gMsg.ID,
+		NeuronId:  neuronId,
+		Stage:     stage,
+		Time:      time.Now(),
+	}
+	if len(detail) > 0 {
+		hop.Detail = fmt.Sprint(detail...)
+	}
+	express.TraceRecord(hop)
+}
+
+//* 写入追踪记录[本地日志及内存] */
+func (express *ExpressS) TraceRecord(hop model.TraceHopS) {
+	if !express.brain.Const.Trace.Enable || hop.Trace == "" {
+		return
+	}
+	express.traceMemory(hop)
+	express.traceWrite(hop)
+}
+
+//* Receiver端记录并上报追踪阶段 */
+func (express *ExpressS) TraceReport(conn *websocket.Conn, gMsg *model.GMessageS, stage string, detail ...interface{}) {
+	if !express.brain.Const.Trace.Enable || gMsg == nil || gMsg.Trace == "" {
+		return
+	}
+	// 记录指令的追踪编号,回复时自动附带
+	if stage == model.TraceReceived {
+		express.traceIds.Set(gMsg.ID, gMsg.Trace)
+		express.traceIdQ.Push(gMsg.ID)
+		for express.traceIdQ.Len() > express.brain.Const.Trace.MemoryLen {
+			if old, found := express.traceIdQ.Shift().(string); found {
+				express.traceIds.Del(old)
+			}
+		}
+	}
+	hop := model.TraceHopS{
+		Trace:     gMsg.Trace,
+		MessageId: gMsg.ID,
+		NeuronId:  express.brain.Const.NeuronId,
+		Stage:     stage,
+		Time:      time.Now(),
+	}
+	if len(detail) > 0 {
+		hop.Detail = fmt.Sprint(detail...)
+	}
+	express.TraceRecord(hop)
+	if conn == nil {
+		return
+	}
+	if err := express.WSWrite(conn, &model.GMessageS{Head: "!", Tag: "TRACE", Cmds: []interface{}{express.brain.Base64Encoder(express.brain.JsonEncoder(hop))}}); err != nil {
+		express.brain.MessageHandler(express.tag, "TraceReport -> WSWrite", 214, err)
+	}
+}
+
+//* Receiver端EVAL执行完成后上报executed阶段[caller -> 调用方,args[0] -> 连接,args[1] -> 指令编号] */
+func (express *ExpressS) TraceExecuted(caller string, args []interface{}, detail ...interface{}) {
+	// 仅记录Commander下发的指令
+	if !express.brain.Const.Trace.Enable || caller != model.RPCCommander || len(args) < 2 {
+		return
+	}
+	conn, found := args[0].(*websocket.Conn)
+	if !found {
+		return
+	}
+	msgId := fmt.Sprint(args[1])
+	trace, found := express.traceIds.Get(msgId).(string)
+	if !found {
+		return
+	}
+	express.TraceReport(conn, &model.GMessageS{ID: msgId, Trace: trace}, model.TraceExecuted, detail...)
+}
+
+//* Commander端接收节点上报的追踪阶段 */
+func (express *ExpressS) TraceHandler(neuronId string, gMsg *model.GMessageS) {
+	if len(gMsg.Cmds) == 0 {
+		return
+	}
+	var hop model.TraceHopS
+	if express.brain.JsonDecoder(express.brain.Base64Decoder(fmt.Sprint(gMsg.Cmds[0])), &hop) == nil {
+		return
+	}
+	// 以连接绑定的节点编号为准
+	hop.NeuronId = neuronId
+	express.TraceRecord(hop)
+}
+
+//* 查询追踪[id -> 追踪编号或指令编号,按时间排序] */
+func (express *ExpressS) Trace(id string) []model.TraceHopS {
+	hops, found := express.traceHub.Get(id).([]model.TraceHopS)
+	if !found {
+		hops = express.traceScan(id)
+	}
+	result := append([]model.TraceHopS{}, hops...)
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Time.Before(result[j].Time)
+	})
+	return result
+}
@@ -0,0 +1,590 @@
+/**
+===========================================================================
+ * 分块文件传输
+ * Chunked file transfer
+ * 发送端 -> ~PUSH[name, size, chunkSize, sha256]
+ * 接收端 -> ~STATUS[missing | ok | fail | denied, Base64(Json)]
+ *   missing -> {Total:缺失总数, Ranges:[[起始, 结束]...]},区间数受WSParam.BufferSize限制,其余在下一轮回复
+ * 发送端 -> ~CHUNK[index, offset, crc32, Base64(data)] ... ~DONE
+ * 接收端校验缺失分块与sha256,缺失则继续重传,ok则移动至目标路径
+ * 拉取 -> ~PULL[path],节点以同一传输编号推送回Commander
+ * 任一端失败 -> ~ABORT[code, reason]
+===========================================================================
+*/
+package frame
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"model"
+	"modules/websocket"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+//* ================================ DEFINE ================================ */
+
+//* 单次传输的分块数上限 */
+const transferMaxChunks = 1 << 20
+
+//* 接收端传输状态 */
+type transferRecvS struct {
+	id        string
+	name      string
+	sum       string
+	size      int64
+	chunkSize int
+	// 完成后移动至的路径
+	dest     string
+	part     string
+	file     *os.File
+	received []bool
+	done     bool
+	// 最近一次回复的缺失区间
+	reported [][2]int
+
+	mutex      sync.Mutex
+	updateTime time.Time
+}
+
+//* 缺失分块[区间首尾均包含] */
+type transferMissingS struct {
+	Total  int
+	Ranges [][2]int
+}
+
+//* 等待中的拉取 */
+type transferPullS struct {
+	neuronId string
+	dir      string
+	resultC  chan model.TransferS
+}
+
+//* ================================ PRIVATE ================================ */
+
+//* 传输根目录下的路径[不可越出根目录] */
+func (express *ExpressS) transferPath(elem ...string) string {
+	return express.brain.PathAbs(path.Join(express.brain.Const.TransferParam.Path, path.Clean("/"+path.Join(elem...))))
+}
+
+//* 分块字节数[Base64及加密后不超过读取缓冲] */
+func (express *ExpressS) transferChunkSize() int {
+	chunkSize := express.brain.Const.TransferParam.ChunkSize
+	if limit := express.brain.Const.WSParam.BufferSize / 2; chunkSize <= 0 || chunkSize > limit {
+		chunkSize = limit
+	}
+	return chunkSize
+}
+
+//* 单次回复的缺失区间数[每个区间Base64后不超过24字节] */
+func (express *ExpressS) transferRangeLimit() int {
+	if limit := express.brain.Const.WSParam.BufferSize / 32; limit > 0 {
+		return limit
+	}
+	return 1
+}
+
+//* 分块数量 */
+func transferChunks(size int64, chunkSize int) int {
+	return int((size + int64(chunkSize) - 1) / int64(chunkSize))
+}
+
+//* 计算文件sha256 */
+func transferSum(file *os.File) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, io.NewSectionReader(file, 0, 1<<62)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+//* 发送传输消息 */
+func (express *ExpressS) transferWrite(ws *websocket.Conn, id string, tag string, cmds ...interface{}) error {
+	return express.WSWrite(ws, &model.GMessageS{ID: id, Head: "~", Tag: tag, Cmds: cmds})
+}
+
+//* 回复接收状态 */
+func (express *ExpressS) transferStatus(ws *websocket.Conn, id string, state string, data interface{}) {
+	if err := express.transferWrite(ws, id, "STATUS", state, express.brain.Base64Encoder(express.brain.JsonEncoder(data))); err != nil {
+		express.brain.MessageHandler(express.tag, "transferStatus -> WSWrite", 214, err)
+	}
+}
+
+//* 推送文件并等待接收端确认[阻塞] */
+func (express *ExpressS) transferSend(ws *websocket.Conn, id string, filePath string) (int, interface{}) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return 216, err.Error()
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		return 216, fmt.Sprintf("Not a File -> %v", path.Base(filePath))
+	}
+	if maxSize := express.brain.Const.TransferParam.MaxSize; maxSize > 0 && info.Size() > maxSize {
+		return 207, fmt.Sprintf("File Too Large -> %d[%d]", info.Size(), maxSize)
+	}
+	sum, err := transferSum(file)
+	if err != nil {
+		return 216, err.Error()
+	}
+	chunkSize := express.transferChunkSize()
+	chunks := transferChunks(info.Size(), chunkSize)
+	statusC := make(chan *model.GMessageS, 4)
+	express.transferSendHub.Set(id, statusC)
+	defer express.transferSendHub.Del(id)
+
+	param := express.brain.Const.TransferParam
+	push := []interface{}{info.Name(), strconv.FormatInt(info.Size(), 10), strconv.Itoa(chunkSize), sum}
+	if err := express.transferWrite(ws, id, "PUSH", push...); err != nil {
+		return 214, err.Error()
+	}
+	buf := make([]byte, chunkSize)
+	lastTotal := -1
+	for round := 0; ; {
+		state, data := "timeout", interface{}(nil)
+		var missing transferMissingS
+		select {
+		case gMsg := <-statusC:
+			state = fmt.Sprint(gMsg.Cmds[0])
+			if len(gMsg.Cmds) > 1 {
+				payload := express.brain.Base64Decoder(fmt.Sprint(gMsg.Cmds[1]))
+				if state == "missing" {
+					express.brain.JsonDecoder(payload, &missing)
+				} else {
+					express.brain.JsonDecoder(payload, &data)
+				}
+			}
+		case <-time.After(time.Duration(param.Timeout) * time.Millisecond):
+		}
+		switch state {
+		case "ok":
+			return 100, data
+		case "denied":
+			return 208, data
+		}
+		// 缺失分块减少则不计入重传轮数[分页回复]
+		if state == "missing" {
+			if lastTotal < 0 || missing.Total < lastTotal {
+				round = 0
+			}
+			lastTotal = missing.Total
+		}
+		round++
+		if round > param.MaxRetry+1 {
+			if state == "timeout" {
+				return 104, "Transfer Timeout"
+			}
+			return 200, fmt.Sprintf("Retry Exceeded -> %v", data)
+		}
+		if state != "missing" {
+			// 超时或校验失败则重新协商,接收端回复当前缺失分块
+			if err := express.transferWrite(ws, id, "PUSH", push...); err != nil {
+				return 214, err.Error()
+			}
+			continue
+		}
+		for _, v := range missing.Ranges {
+			for index := v[0]; index <= v[1] && index < chunks; index++ {
+				if index < 0 {
+					continue
+				}
+				offset := int64(index) * int64(chunkSize)
+				n, err := file.ReadAt(buf, offset)
+				if err != nil && err != io.EOF {
+					return 216, err.Error()
+				}
+				chunk := []interface{}{strconv.Itoa(index), strconv.FormatInt(offset, 10), strconv.FormatUint(uint64(crc32.ChecksumIEEE(buf[:n])), 10), express.brain.Base64Encoder(buf[:n])}
+				if err := express.transferWrite(ws, id, "CHUNK", chunk...); err != nil {
+					return 214, err.Error()
+				}
+			}
+		}
+		if err := express.transferWrite(ws, id, "DONE"); err != nil {
+			return 214, err.Error()
+		}
+	}
+}
+
+//* 发送失败时通知对端 */
+func (express *ExpressS) transferAbort(ws *websocket.Conn, id string, code int, data interface{}) {
+	express.transferWrite(ws, id, "ABORT", strconv.Itoa(code), fmt.Sprint(data))
+}
+
+//* 接收端开始或恢复传输 */
+func (express *ExpressS) transferOpen(ws *websocket.Conn, gMsg *model.GMessageS, pushable bool) {
+	pull, isPull := express.transferPullHub.Get(gMsg.ID).(*transferPullS)
+	if !pushable && !isPull {
+		express.transferStatus(ws, gMsg.ID, "denied", "Push Not Allowed")
+		return
+	}
+	if len(gMsg.Cmds) < 4 {
+		express.transferStatus(ws, gMsg.ID, "denied", "Lack of Parameter")
+		return
+	}
+	name := path.Base(path.Clean("/" + fmt.Sprint(gMsg.Cmds[0])))
+	size, errSize := strconv.ParseInt(fmt.Sprint(gMsg.Cmds[1]), 10, 64)
+	chunkSize, errChunk := strconv.Atoi(fmt.Sprint(gMsg.Cmds[2]))
+	sum := fmt.Sprint(gMsg.Cmds[3])
+	if name == "/" || errSize != nil || errChunk != nil || size < 0 || chunkSize <= 0 || chunkSize > express.brain.Const.WSParam.BufferSize {
+		express.transferStatus(ws, gMsg.ID, "denied", "Parameter Error")
+		return
+	}
+	if maxSize := express.brain.Const.TransferParam.MaxSize; (maxSize > 0 && size > maxSize) || transferChunks(size, chunkSize) > transferMaxChunks {
+		express.transferStatus(ws, gMsg.ID, "denied", "File Too Large")
+		return
+	}
+	express.transferExpire()
+	// 同一传输编号且文件一致则从已接收分块继续
+	if recv, found := express.transferRecvHub.Get(gMsg.ID).(*transferRecvS); found {
+		recv.mutex.Lock()
+		same := recv.sum == sum && recv.size == size && recv.chunkSize == chunkSize
+		recv.mutex.Unlock()
+		if same {
+			express.transferReport(ws, recv)
+			return
+		}
+		express.transferDrop(gMsg.ID)
+	}
+	dest := express.transferPath(name)
+	if isPull {
+		dest = path.Join(pull.dir, name)
+	}
+	part := express.transferPath(".part", gMsg.ID)
+	if code, err := express.brain.PathCreate(path.Dir(part)); code != 100 {
+		express.transferStatus(ws, gMsg.ID, "denied", fmt.Sprint(err))
+		return
+	}
+	file, err := os.OpenFile(part, os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.FileMode(express.brain.Const.File.Chmod))
+	if err == nil {
+		if err = file.Truncate(size); err != nil {
+			file.Close()
+		}
+	}
+	if err != nil {
+		express.brain.MessageHandler(express.tag, "transferOpen -> OpenFile", 216, err)
+		express.transferStatus(ws, gMsg.ID, "denied", err.Error())
+		return
+	}
+	recv := &transferRecvS{
+		id:         gMsg.ID,
+		name:       name,
+		sum:        sum,
+		size:       size,
+		chunkSize:  chunkSize,
+		dest:       dest,
+		part:       part,
+		file:       file,
+		received:   make([]bool, transferChunks(size, chunkSize)),
+		updateTime: time.Now(),
+	}
+	express.transferRecvHub.Set(gMsg.ID, recv)
+	express.transferReport(ws, recv)
+}
+
+//* 回复接收进度[完成则回复ok] */
+func (express *ExpressS) transferReport(ws *websocket.Conn, recv *transferRecvS) {
+	recv.mutex.Lock()
+	recv.updateTime = time.Now()
+	if recv.done {
+		recv.mutex.Unlock()
+		express.transferStatus(ws, recv.id, "ok", recv.name)
+		return
+	}
+	ranges, total := recv.missing(express.transferRangeLimit())
+	recv.reported = ranges
+	recv.mutex.Unlock()
+	express.transferStatus(ws, recv.id, "missing", transferMissingS{Total: total, Ranges: ranges})
+}
+
+//* 缺失分块区间[最多limit个,返回缺失总数,需持有锁] */
+func (recv *transferRecvS) missing(limit int) ([][2]int, int) {
+	ranges := make([][2]int, 0)
+	total := 0
+	for k := 0; k < len(recv.received); k++ {
+		if recv.received[k] {
+			continue
+		}
+		start := k
+		for k+1 < len(recv.received) && !recv.received[k+1] {
+			k++
+		}
+		total += k - start + 1
+		if len(ranges) < limit {
+			ranges = append(ranges, [2]int{start, k})
+		}
+	}
+	return ranges, total
+}
+
+//* 最近一次回复的区间是否仍有缺失[需持有锁] */
+func (recv *transferRecvS) reportedMissing() bool {
+	for _, v := range recv.reported {
+		for index := v[0]; index <= v[1] && index < len(recv.received); index++ {
+			if !recv.received[index] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//* 写入分块 */
+func (express *ExpressS) transferChunk(gMsg *model.GMessageS) {
+	recv, found := express.transferRecvHub.Get(gMsg.ID).(*transferRecvS)
+	if !found || len(gMsg.Cmds) < 4 {
+		return
+	}
+	index, errIndex := strconv.Atoi(fmt.Sprint(gMsg.Cmds[0]))
+	offset, errOffset := strconv.ParseInt(fmt.Sprint(gMsg.Cmds[1]), 10, 64)
+	crc, errCrc := strconv.ParseUint(fmt.Sprint(gMsg.Cmds[2]), 10, 32)
+	if errIndex != nil || errOffset != nil || errCrc != nil {
+		express.brain.MessageHandler(express.tag, "transferChunk", 221, gMsg.ID)
+		return
+	}
+	data := express.brain.Base64Decoder(fmt.Sprint(gMsg.Cmds[3]))
+	recv.mutex.Lock()
+	defer recv.mutex.Unlock()
+	recv.updateTime = time.Now()
+	if recv.done || index < 0 || index >= len(recv.received) || offset != int64(index)*int64(recv.chunkSize) || offset+int64(len(data)) > recv.size {
+		return
+	}
+	// 校验失败的分块保持缺失,等待重传
+	if uint32(crc) != crc32.ChecksumIEEE(data) {
+		express.brain.MessageHandler(express.tag, "transferChunk -> CRC", 209, fmt.Sprintf("[%v] %v", gMsg.ID, index))
+		return
+	}
+	if _, err := recv.file.WriteAt(data, offset); err != nil {
+		express.brain.MessageHandler(express.tag, "transferChunk -> WriteAt", 216, err)
+		return
+	}
+	recv.received[index] = true
+}
+
+//* 发送端本轮发送完毕 */
+func (express *ExpressS) transferDone(ws *websocket.Conn, gMsg *model.GMessageS) {
+	recv, found := express.transferRecvHub.Get(gMsg.ID).(*transferRecvS)
+	if !found {
+		return
+	}
+	// Receiver端消息并发处理,等待本轮仍在写入的分块
+	for i := 0; i < 20; i++ {
+		recv.mutex.Lock()
+		pending := recv.reportedMissing()
+		recv.mutex.Unlock()
+		if !pending {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	recv.mutex.Lock()
+	if _, total := recv.missing(0); recv.done || total > 0 {
+		recv.mutex.Unlock()
+		express.transferReport(ws, recv)
+		return
+	}
+	sum, err := transferSum(recv.file)
+	if err != nil || sum != recv.sum {
+		// 整体校验失败,重置全部分块
+		recv.received = make([]bool, len(recv.received))
+		recv.mutex.Unlock()
+		express.brain.MessageHandler(express.tag, "transferDone -> Checksum", 209, recv.id)
+		express.transferStatus(ws, recv.id, "fail", "Checksum Error")
+		return
+	}
+	recv.file.Close()
+	code, data := express.brain.PathCreate(path.Dir(recv.dest))
+	if code == 100 {
+		code, data = express.brain.FileMove(recv.part, recv.dest)
+	}
+	if code != 100 {
+		recv.mutex.Unlock()
+		express.brain.MessageHandler(express.tag, "transferDone -> FileMove", 216, data)
+		express.transferAbort(ws, recv.id, 216, "Save Failed")
+		express.transferDrop(recv.id)
+		express.transferPullResolve(recv.id, 216, "Save Failed")
+		return
+	}
+	recv.done = true
+	recv.updateTime = time.Now()
+	recv.mutex.Unlock()
+	express.transferStatus(ws, recv.id, "ok", recv.name)
+	express.transferPullResolve(recv.id, 100, recv.dest)
+}
+
+//* 丢弃接收状态及临时文件 */
+func (express *ExpressS) transferDrop(id string) {
+	recv, found := express.transferRecvHub.Pop(id).(*transferRecvS)
+	if !found {
+		return
+	}
+	recv.mutex.Lock()
+	defer recv.mutex.Unlock()
+	if !recv.done {
+		recv.file.Close()
+		os.Remove(recv.part)
+	}
+}
+
+//* 清理过期的接收状态 */
+func (express *ExpressS) transferExpire() {
+	expire := time.Duration(express.brain.Const.TransferParam.Expire) * time.Millisecond
+	expired := make([]string, 0)
+	express.transferRecvHub.Iterator(func(n int, k string, v interface{}) bool {
+		if recv, found := v.(*transferRecvS); found {
+			recv.mutex.Lock()
+			if time.Since(recv.updateTime) > expire {
+				expired = append(expired, k)
+			}
+			recv.mutex.Unlock()
+		}
+		return true
+	})
+	for _, v := range expired {
+		express.transferDrop(v)
+	}
+}
+
+//* 交付拉取结果 */
+func (express *ExpressS) transferPullResolve(id string, code int, data interface{}) {
+	pull, found := express.transferPullHub.Pop(id).(*transferPullS)
+	if !found {
+		return
+	}
+	pull.resultC <- model.TransferS{Id: id, NeuronId: pull.neuronId, Code: code, Data: data}
+}
+
+//* ================================ PUBLIC ================================ */
+
+//* 处理传输消息[pushable -> 是否接受对端主动推送及拉取,仅Receiver端] */
+func (express *ExpressS) TransferHandler(ws *websocket.Conn, gMsg *model.GMessageS, pushable bool) {
+	switch gMsg.Tag {
+	case "PUSH":
+		express.transferOpen(ws, gMsg, pushable)
+	case "CHUNK":
+		express.transferChunk(gMsg)
+	case "DONE":
+		express.transferDone(ws, gMsg)
+	case "STATUS":
+		if len(gMsg.Cmds) == 0 {
+			return
+		}
+		if statusC, found := express.transferSendHub.Get(gMsg.ID).(chan *model.GMessageS); found {
+			select {
+			case statusC <- gMsg:
+			default:
+			}
+		}
+	case "PULL":
+		if !pushable || len(gMsg.Cmds) == 0 {
+			express.transferAbort(ws, gMsg.ID, 208, "Pull Not Allowed")
+			return
+		}
+		go express.brain.SafeFunction(func() {
+			if code, data := express.transferSend(ws, gMsg.ID, express.transferPath(fmt.Sprint(gMsg.Cmds[0]))); code != 100 {
+				express.brain.MessageHandler(express.tag, "TransferHandler -> PULL", code, data)
+				express.transferAbort(ws, gMsg.ID, code, data)
+			}
+		})
+	case "ABORT":
+		code, reason := 200, ""
+		if len(gMsg.Cmds) > 1 {
+			if n, err := strconv.Atoi(fmt.Sprint(gMsg.Cmds[0])); err == nil {
+				code = n
+			}
+			reason = fmt.Sprint(gMsg.Cmds[1])
+		}
+		express.transferDrop(gMsg.ID)
+		express.transferPullResolve(gMsg.ID, code, reason)
+	}
+}
+
+//* 推送文件至路由表达式选中的节点[filePath相对TransferParam.Path] */
+func (express *ExpressS) TransferPush(route string, filePath string) []model.TransferS {
+	localPath := express.transferPath(filePath)
+	var size int64
+	if info, err := os.Stat(localPath); err == nil {
+		size = info.Size()
+	}
+	nodes := express.RouteNodes(route)
+	results := make([]model.TransferS, len(nodes))
+	var wg sync.WaitGroup
+	for k, v := range nodes {
+		wg.Add(1)
+		go func(k int, neuronId string) {
+			defer wg.Done()
+			result := model.TransferS{Id: express.brain.UUID(), NeuronId: neuronId, Name: path.Base(localPath), Size: size, StartTime: time.Now()}
+			if ws := express.tunnelNodeConn(neuronId); ws == nil {
+				result.Code, result.Data = 214, "Node Offline -> "+neuronId
+			} else {
+				express.brain.SafeFunction(func() {
+					result.Code, result.Data = express.transferSend(ws, result.Id, localPath)
+				})
+				if result.Code != 100 {
+					express.transferAbort(ws, result.Id, result.Code, result.Data)
+				}
+			}
+			result.EndTime = time.Now()
+			results[k] = result
+		}(k, v.NeuronId)
+	}
+	wg.Wait()
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].NeuronId < results[j].NeuronId
+	})
+	return results
+}
+
+//* 从节点拉取文件[保存至TransferParam.Path/<NeuronId>/] */
+func (express *ExpressS) TransferPull(neuronId string, filePath string) (result model.TransferS) {
+	result = model.TransferS{Id: express.brain.UUID(), NeuronId: neuronId, Name: path.Base(path.Clean("/" + filePath)), StartTime: time.Now()}
+	defer func() {
+		result.EndTime = time.Now()
+	}()
+	ws := express.tunnelNodeConn(neuronId)
+	if ws == nil {
+		result.Code, result.Data = 214, "Node Offline -> "+neuronId
+		return result
+	}
+	pull := &transferPullS{neuronId, express.transferPath(neuronId), make(chan model.TransferS, 1)}
+	express.transferPullHub.Set(result.Id, pull)
+	defer express.transferPullHub.Del(result.Id)
+	if err := express.transferWrite(ws, result.Id, "PULL", filePath); err != nil {
+		result.Code, result.Data = 214, err.Error()
+		return result
+	}
+	// 接收期间持续有分块到达则继续等待
+	param := express.brain.Const.TransferParam
+	idle := time.Duration(param.Timeout*(param.MaxRetry+1)) * time.Millisecond
+	ticker := time.NewTicker(time.Duration(param.Timeout) * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case v := <-pull.resultC:
+			result.Code, result.Data = v.Code, v.Data
+			if recv, found := express.transferRecvHub.Get(result.Id).(*transferRecvS); found {
+				result.Size = recv.size
+			}
+			return result
+		case <-ticker.C:
+			updateTime := result.StartTime
+			if recv, found := express.transferRecvHub.Get(result.Id).(*transferRecvS); found {
+				recv.mutex.Lock()
+				updateTime = recv.updateTime
+				recv.mutex.Unlock()
+			}
+			if time.Since(updateTime) > idle {
+				express.transferDrop(result.Id)
+				result.Code, result.Data = 104, "Transfer Timeout"
+				return result
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package frame
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTransferMissing(t *testing.T) {
+	cases := []struct {
+		name     string
+		received []bool
+		limit    int
+		ranges   [][2]int
+		total    int
+	}{
+		{"none", []bool{true, true}, 4, [][2]int{}, 0},
+		{"all", []bool{false, false, false}, 4, [][2]int{{0, 2}}, 3},
+		{"holes", []bool{false, true, false, false, true, false}, 4, [][2]int{{0, 0}, {2, 3}, {5, 5}}, 4},
+		{"paged", []bool{false, true, false, true, false}, 2, [][2]int{{0, 0}, {2, 2}}, 3},
+		{"countOnly", []bool{false, true, false}, 0, [][2]int{}, 2},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			recv := &transferRecvS{received: c.received}
+			ranges, total := recv.missing(c.limit)
+			if !reflect.DeepEqual(ranges, c.ranges) || total != c.total {
+				t.Fatalf("missing = %v[%d], want %v[%d]", ranges, total, c.ranges, c.total)
+			}
+		})
+	}
+}
+
+func TestTransferReportedMissing(t *testing.T) {
+	recv := &transferRecvS{received: []bool{false, true, false, false}}
+	recv.reported, _ = recv.missing(1)
+	recv.received[0] = true
+	// 本轮区间已全部写入,其余缺失留待下一轮
+	if recv.reportedMissing() {
+		t.Fatal("reported range still missing")
+	}
+	recv.reported = [][2]int{{2, 3}}
+	if !recv.reportedMissing() {
+		t.Fatal("reported range should be missing")
+	}
+}
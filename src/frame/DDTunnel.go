@@ -0,0 +1,485 @@
+/**
+===========================================================================
+ * 反向隧道 -> 经Commander/Receiver通道复用TCP流
+ * Commander监听端口,每个连接作为一个流转发至节点内网目标
+ * ~OPEN  -> ID:流编号 Cmds:[目标地址]
+ * ~DATA  -> ID:流编号 Cmds:[序号, Base64(数据)]
+ * ~CLOSE -> ID:流编号 Cmds:[已发送数据帧数, 原因]
+ * ~ACK   -> ID:流编号 Cmds:[已写入数据帧数]
+ * Receiver端消息并发处理,数据按序号重排后由各流独立协程写入
+ * 未确认数据帧不超过tunnelWindow,发送端等待确认,接收端超出窗口则关闭流
+===========================================================================
+*/
+package frame
+
+import (
+	"fmt"
+	"model"
+	"modules/websocket"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//* ================================ DEFINE ================================ */
+
+//* 已关闭流的记录上限 */
+const tunnelClosedLen = 1 << 12
+
+//* 流量窗口[未确认数据帧数]及确认间隔 */
+const (
+	tunnelWindow   = 256
+	tunnelAckEvery = tunnelWindow / 4
+)
+
+//* 隧道监听 */
+type tunnelListenerS struct {
+	tunnel   model.TunnelS
+	listener net.Listener
+}
+
+//* 隧道流 */
+type tunnelStreamS struct {
+	// 流注册键[两端同进程时区分]
+	key      string
+	id       string
+	tunnelId string
+	conn     net.Conn
+	ws       *websocket.Conn
+	// 连接就绪[Receiver端拨号完成或流已关闭]
+	ready     chan bool
+	readyOnce sync.Once
+	// 流已关闭
+	done chan bool
+	// 发送序号及对端已确认写入的帧数
+	sendSeq uint64
+	ackSeq  uint64
+	ackC    chan bool
+	// 接收重排[乱序及待写入的帧均在窗口内]
+	mutex   sync.Mutex
+	recvSeq uint64
+	recvBuf map[uint64][]byte
+	writeC  chan []byte
+	written uint64
+	closeAt int64
+	// 已全部交付,writeC已关闭
+	flushed bool
+}
+
+//* ================================ PRIVATE ================================ */
+
+//* 隧道流注册键[Receiver端加前缀,Commander与Receiver同进程时互不干扰] */
+func tunnelKey(id string, dialable bool) string {
+	if dialable {
+		return "~" + id
+	}
+	return id
+}
+
+//* 获取或新建隧道流[已关闭则返回nil] */
+func (express *ExpressS) tunnelStream(id string, ws *websocket.Conn, dialable bool) *tunnelStreamS {
+	key := tunnelKey(id, dialable)
+	if express.streamClosed.Get(key) != nil {
+		return nil
+	}
+	streamI, loaded := express.streamHub.GetOrSet(key, &tunnelStreamS{
+		key:     key,
+		id:      id,
+		ws:      ws,
+		ready:   make(chan bool),
+		done:    make(chan bool),
+		ackC:    make(chan bool, 1),
+		recvBuf: make(map[uint64][]byte),
+		writeC:  make(chan []byte, tunnelWindow),
+		closeAt: -1,
+	})
+	stream := streamI.(*tunnelStreamS)
+	if !loaded {
+		go express.brain.SafeFunction(func() {
+			express.tunnelWriter(stream)
+		})
+	}
+	return stream
+}
+
+//* 绑定本地连接并标记就绪 */
+func (express *ExpressS) tunnelReady(stream *tunnelStreamS, conn net.Conn) {
+	stream.mutex.Lock()
+	stream.conn = conn
+	stream.mutex.Unlock()
+	stream.readyOnce.Do(func() {
+		close(stream.ready)
+	})
+}
+
+//* 查找节点连接 */
+func (express *ExpressS) tunnelNodeConn(neuronId string) *websocket.Conn {
+	var conn *websocket.Conn
+	express.WSBroadcast(func(rank int, ip string, tag string, ws *websocket.Conn) {
+		if conn == nil && tag == neuronId {
+			conn = ws
+		}
+	}, express.brain.Container.CommanderHub)
+	return conn
+}
+
+//* Commander端接收本地连接 */
+func (express *ExpressS) tunnelAccept(tunnel model.TunnelS, conn net.Conn) {
+	ws := express.tunnelNodeConn(tunnel.NeuronId)
+	if ws == nil {
+		express.brain.MessageHandler(express.tag, "tunnelAccept", 214, fmt.Sprintf("Node Offline -> %v", tunnel.NeuronId))
+		conn.Close()
+		return
+	}
+	stream := express.tunnelStream(express.brain.UUID(), ws, false)
+	stream.tunnelId = tunnel.Id
+	express.tunnelReady(stream, conn)
+	if err := express.WSWrite(ws, &model.GMessageS{ID: stream.id, Head: "~", Tag: "OPEN", Cmds: []interface{}{tunnel.Target}}); err != nil {
+		express.brain.MessageHandler(express.tag, "tunnelAccept -> WSWrite", 214, err)
+		express.tunnelStreamClose(stream.key, false, "")
+		return
+	}
+	express.tunnelPipe(stream)
+}
+
+//* Receiver端拨号目标地址 */
+func (express *ExpressS) tunnelDial(stream *tunnelStreamS, target string) {
+	if !express.tunnelAllowed(target) {
+		express.brain.MessageHandler(express.tag, "tunnelDial -> Not Allowed", 208, target)
+		express.tunnelStreamClose(stream.key, true, "Target Not Allowed")
+		return
+	}
+	conn, err := net.DialTimeout("tcp", target, time.Duration(express.brain.Const.TCPParam.Interval)*time.Millisecond)
+	if err != nil {
+		express.brain.MessageHandler(express.tag, "tunnelDial -> Dial", 210, err)
+		express.tunnelStreamClose(stream.key, true, err.Error())
+		return
+	}
+	express.tunnelReady(stream, conn)
+	// 拨号期间流已关闭
+	if express.streamHub.Get(stream.key) == nil {
+		conn.Close()
+		return
+	}
+	express.tunnelPipe(stream)
+}
+
+//* 判断目标地址是否允许 */
+func (express *ExpressS) tunnelAllowed(target string) bool {
+	for _, v := range express.brain.Const.Proxy.TunnelAllow {
+		if v == "*" || v == target {
+			return true
+		}
+	}
+	return false
+}
+
+//* 读取本地连接并发送至对端 */
+func (express *ExpressS) tunnelPipe(stream *tunnelStreamS) {
+	buf := make([]byte, 32<<10)
+	reason := ""
+	for {
+		// 等待对端确认
+		for atomic.LoadUint64(&stream.sendSeq)-atomic.LoadUint64(&stream.ackSeq) >= tunnelWindow {
+			select {
+			case <-stream.ackC:
+			case <-stream.done:
+				return
+			}
+		}
+		n, err := stream.conn.Read(buf)
+		if n > 0 {
+			gMsg := &model.GMessageS{ID: stream.id, Head: "~", Tag: "DATA", Cmds: []interface{}{strconv.FormatUint(atomic.LoadUint64(&stream.sendSeq), 10), express.brain.Base64Encoder(buf[:n])}}
+			if err := express.WSWrite(stream.ws, gMsg); err != nil {
+				reason = err.Error()
+				break
+			}
+			atomic.AddUint64(&stream.sendSeq, 1)
+		}
+		if err != nil {
+			break
+		}
+	}
+	express.tunnelStreamClose(stream.key, true, reason)
+}
+
+//* 按序交付对端数据至写入协程 */
+func (express *ExpressS) tunnelDeliver(stream *tunnelStreamS, seq uint64, data []byte) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	if stream.flushed || seq < stream.recvSeq {
+		return
+	}
+	// 超出窗口
+	if seq >= atomic.LoadUint64(&stream.written)+tunnelWindow {
+		go express.tunnelStreamClose(stream.key, true, "Window Exceeded")
+		return
+	}
+	stream.recvBuf[seq] = data
+	for {
+		chunk, found := stream.recvBuf[stream.recvSeq]
+		if !found {
+			break
+		}
+		delete(stream.recvBuf, stream.recvSeq)
+		stream.recvSeq++
+		select {
+		case stream.writeC <- chunk:
+		default:
+			go express.tunnelStreamClose(stream.key, true, "Window Exceeded")
+			return
+		}
+	}
+	express.tunnelFlush(stream)
+}
+
+//* 对端已关闭且数据已全部交付则结束写入[须持有stream.mutex] */
+func (express *ExpressS) tunnelFlush(stream *tunnelStreamS) {
+	if !stream.flushed && stream.closeAt >= 0 && int64(stream.recvSeq) >= stream.closeAt {
+		stream.flushed = true
+		close(stream.writeC)
+	}
+}
+
+//* 写入本地连接[每个流独立协程,慢连接不阻塞通道] */
+func (express *ExpressS) tunnelWriter(stream *tunnelStreamS) {
+	// 等待拨号完成
+	select {
+	case <-stream.ready:
+	case <-stream.done:
+		return
+	case <-time.After(2 * time.Duration(express.brain.Const.TCPParam.Interval) * time.Millisecond):
+		express.tunnelStreamClose(stream.key, true, "Open Timeout")
+		return
+	}
+	stream.mutex.Lock()
+	conn := stream.conn
+	stream.mutex.Unlock()
+	if conn == nil {
+		return
+	}
+	for {
+		select {
+		case <-stream.done:
+			return
+		case chunk, found := <-stream.writeC:
+			// 对端已关闭且数据已全部写入
+			if !found {
+				express.tunnelStreamClose(stream.key, false, "")
+				return
+			}
+			if _, err := conn.Write(chunk); err != nil {
+				express.tunnelStreamClose(stream.key, true, err.Error())
+				return
+			}
+			if written := atomic.AddUint64(&stream.written, 1); written%tunnelAckEvery == 0 {
+				if err := express.WSWrite(stream.ws, &model.GMessageS{ID: stream.id, Head: "~", Tag: "ACK", Cmds: []interface{}{strconv.FormatUint(written, 10)}}); err != nil {
+					express.tunnelStreamClose(stream.key, false, "")
+					return
+				}
+			}
+		}
+	}
+}
+
+//* 对端确认写入 */
+func (express *ExpressS) tunnelAck(stream *tunnelStreamS, written uint64) {
+	for {
+		acked := atomic.LoadUint64(&stream.ackSeq)
+		if written <= acked || atomic.CompareAndSwapUint64(&stream.ackSeq, acked, written) {
+			break
+		}
+	}
+	select {
+	case stream.ackC <- true:
+	default:
+	}
+}
+
+//* 关闭隧道流[notify -> 通知对端] */
+func (express *ExpressS) tunnelStreamClose(key string, notify bool, reason string) {
+	stream, found := express.streamHub.Pop(key).(*tunnelStreamS)
+	if !found {
+		return
+	}
+	// 记录已关闭的流,忽略迟到的消息
+	express.streamClosed.Set(key, true)
+	express.streamClosedQ.Push(key)
+	for express.streamClosedQ.Len() > tunnelClosedLen {
+		if old, found := express.streamClosedQ.Shift().(string); found {
+			express.streamClosed.Del(old)
+		}
+	}
+	stream.readyOnce.Do(func() {
+		close(stream.ready)
+	})
+	close(stream.done)
+	stream.mutex.Lock()
+	if stream.conn != nil {
+		stream.conn.Close()
+	}
+	stream.mutex.Unlock()
+	if notify {
+		express.WSWrite(stream.ws, &model.GMessageS{ID: stream.id, Head: "~", Tag: "CLOSE", Cmds: []interface{}{strconv.FormatUint(atomic.LoadUint64(&stream.sendSeq), 10), reason}})
+	}
+}
+
+//* 对端请求关闭[等待未到达的数据帧] */
+func (express *ExpressS) tunnelRemoteClose(stream *tunnelStreamS, sent int64, reason string) {
+	if reason != "" {
+		express.brain.MessageHandler(express.tag, "tunnelRemoteClose", 216, fmt.Sprintf("[%v] %v", stream.id, reason))
+	}
+	if sent < 0 {
+		express.tunnelStreamClose(stream.key, false, "")
+		return
+	}
+	// 写入协程写完剩余数据后关闭
+	stream.mutex.Lock()
+	stream.closeAt = sent
+	express.tunnelFlush(stream)
+	stream.mutex.Unlock()
+}
+
+//* 释放连接上的全部隧道流 */
+func (express *ExpressS) tunnelRelease(ws *websocket.Conn) {
+	express.streamHub.Iterator(func(n int, k string, v interface{}) bool {
+		if stream, found := v.(*tunnelStreamS); found && stream.ws == ws {
+			go express.tunnelStreamClose(k, false, "")
+		}
+		return true
+	})
+}
+
+//* ================================ PUBLIC ================================ */
+
+//* 处理隧道消息[dialable -> 是否允许OPEN,仅Receiver端] */
+func (express *ExpressS) TunnelHandler(ws *websocket.Conn, gMsg *model.GMessageS, dialable bool) {
+	// Receiver端消息可能乱序到达,先建立占位流
+	var stream *tunnelStreamS
+	if dialable {
+		stream = express.tunnelStream(gMsg.ID, ws, true)
+	} else {
+		stream, _ = express.streamHub.Get(tunnelKey(gMsg.ID, false)).(*tunnelStreamS)
+	}
+	if stream == nil {
+		return
+	}
+	// 流仅接收所属连接上的消息
+	if stream.ws != ws {
+		express.brain.MessageHandler(express.tag, "TunnelHandler -> Connection Mismatch", 208, fmt.Sprintf("[%v] %v", gMsg.ID, gMsg.Tag))
+		return
+	}
+	switch gMsg.Tag {
+	case "OPEN":
+		if !dialable || len(gMsg.Cmds) == 0 {
+			express.brain.MessageHandler(express.tag, "TunnelHandler -> OPEN", 208, gMsg.ID)
+			return
+		}
+		go express.brain.SafeFunction(func() {
+			express.tunnelDial(stream, fmt.Sprint(gMsg.Cmds[0]))
+		})
+	case "DATA":
+		if len(gMsg.Cmds) < 2 {
+			return
+		}
+		seq, err := strconv.ParseUint(fmt.Sprint(gMsg.Cmds[0]), 10, 64)
+		if err != nil {
+			express.brain.MessageHandler(express.tag, "TunnelHandler -> DATA", 221, err)
+			return
+		}
+		express.tunnelDeliver(stream, seq, express.brain.Base64Decoder(fmt.Sprint(gMsg.Cmds[1])))
+	case "ACK":
+		if len(gMsg.Cmds) == 0 {
+			return
+		}
+		written, err := strconv.ParseUint(fmt.Sprint(gMsg.Cmds[0]), 10, 64)
+		if err != nil {
+			express.brain.MessageHandler(express.tag, "TunnelHandler -> ACK", 221, err)
+			return
+		}
+		express.tunnelAck(stream, written)
+	case "CLOSE":
+		sent, reason := int64(-1), ""
+		if len(gMsg.Cmds) > 0 {
+			if n, err := strconv.ParseInt(fmt.Sprint(gMsg.Cmds[0]), 10, 64); err == nil {
+				sent = n
+			}
+		}
+		if len(gMsg.Cmds) > 1 {
+			reason = fmt.Sprint(gMsg.Cmds[1])
+		}
+		express.tunnelRemoteClose(stream, sent, reason)
+	}
+}
+
+//* 开启反向隧道[Commander监听listen,经neuronId节点连接target] */
+func (express *ExpressS) TunnelOpen(listen string, neuronId string, target string) (int, interface{}) {
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return 210, err
+	}
+	tunnel := model.TunnelS{
+		Id:         express.brain.UUID(),
+		Listen:     listener.Addr().String(),
+		NeuronId:   neuronId,
+		Target:     target,
+		CreateTime: time.Now(),
+	}
+	express.tunnelHub.Set(tunnel.Id, tunnelListenerS{tunnel, listener})
+	go express.brain.SafeFunction(func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				express.brain.LogGenerater(model.LogTrace, express.tag, "TunnelOpen", fmt.Sprintf("Listener Closed -> %v", tunnel.Listen))
+				return
+			}
+			go express.brain.SafeFunction(func() {
+				express.tunnelAccept(tunnel, conn)
+			})
+		}
+	})
+	return 100, tunnel
+}
+
+//* 关闭反向隧道及其全部流 */
+func (express *ExpressS) TunnelClose(tunnelId string) (int, interface{}) {
+	item, found := express.tunnelHub.Pop(tunnelId).(tunnelListenerS)
+	if !found {
+		return 220, "Tunnel Not Found -> " + tunnelId
+	}
+	item.listener.Close()
+	express.streamHub.Iterator(func(n int, k string, v interface{}) bool {
+		if stream, found := v.(*tunnelStreamS); found && stream.tunnelId == tunnelId {
+			go express.tunnelStreamClose(k, true, "Tunnel Closed")
+		}
+		return true
+	})
+	return 100, item.tunnel
+}
+
+//* 获取全部反向隧道 */
+func (express *ExpressS) Tunnels() []model.TunnelS {
+	streams := make(map[string]int)
+	express.streamHub.Iterator(func(n int, k string, v interface{}) bool {
+		if stream, found := v.(*tunnelStreamS); found {
+			streams[stream.tunnelId]++
+		}
+		return true
+	})
+	tunnels := make([]model.TunnelS, 0, express.tunnelHub.Len())
+	express.tunnelHub.Iterator(func(n int, k string, v interface{}) bool {
+		if item, found := v.(tunnelListenerS); found {
+			tunnel := item.tunnel
+			tunnel.Streams = streams[tunnel.Id]
+			tunnels = append(tunnels, tunnel)
+		}
+		return true
+	})
+	sort.Slice(tunnels, func(i, j int) bool {
+		return tunnels[i].CreateTime.Before(tunnels[j].CreateTime)
+	})
+	return tunnels
+}
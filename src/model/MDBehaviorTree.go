@@ -10,9 +10,9 @@ package model
 
 type BehaviorForestS struct {
 	// 行为树编号
-	UUIDQ *QueueS /* UUID */
+	UUIDQ QueueI /* UUID */
 	// 行为树容器
-	Trees SyncMapHub /* map[UUID]*model.BehaviorTreeS */
+	Trees MapI /* map[UUID]*model.BehaviorTreeS */
 	// 错误容器
 	ErrorQ *QueueS /* string */
 }
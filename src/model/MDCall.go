@@ -0,0 +1,126 @@
+/**
+===========================================================================
+ * 远程调用凭证
+ * Remote call future
+===========================================================================
+*/
+package model
+
+import (
+	"reflect"
+	"sync"
+	"time"
+)
+
+//* 远程调用凭证 */
+type CallS struct {
+	// GMessage编号
+	ID       string
+	NeuronId string
+	Service  string
+	Function string
+	// 发起时间
+	Timestamp time.Time
+	// 超时时间
+	Deadline time.Time
+	// 调用结果[100 -> 回复 | 103 -> 取消 | 104 -> 超时 | 其他 -> 远程拒绝]
+	Code  int
+	Reply *CommanderPiece
+
+	done chan bool
+	once *sync.Once
+}
+
+//* 新建调用凭证 */
+func (call *CallS) New(id, neuronId, service, function string, timeout int) *CallS {
+	now := time.Now()
+	return &CallS{
+		ID:        id,
+		NeuronId:  neuronId,
+		Service:   service,
+		Function:  function,
+		Timestamp: now,
+		Deadline:  now.Add(time.Duration(timeout) * time.Millisecond),
+		done:      make(chan bool),
+		once:      new(sync.Once),
+	}
+}
+
+//* 写入调用结果[仅首次生效] */
+func (call *CallS) Resolve(code int, reply *CommanderPiece) bool {
+	resolved := false
+	call.once.Do(func() {
+		call.Code = code
+		call.Reply = reply
+		resolved = true
+		close(call.done)
+	})
+	return resolved
+}
+
+//* 调用完成通道 */
+func (call *CallS) Done() <-chan bool {
+	return call.done
+}
+
+//* 阻塞等待调用结果 */
+func (call *CallS) Wait() (int, *CommanderPiece) {
+	<-call.done
+	return call.Code, call.Reply
+}
+
+//* 判断是否超时 */
+func (call *CallS) IsExpired(now time.Time) bool {
+	return now.After(call.Deadline)
+}
+
+//* 广播汇总结果 */
+type GatherS struct {
+	Route string
+	// 已回复节点[NeuronId -> 回复]
+	Replies map[string]*CommanderPiece
+	// 超时或取消的节点
+	Timeout []string
+	// 拒绝或失败的节点[NeuronId -> 错误码及数据]
+	Errors map[string]GatherErrorS
+	// 是否达到法定回复数
+	Quorum bool
+}
+
+//* 广播汇总中的节点错误 */
+type GatherErrorS struct {
+	Code int
+	Data interface{}
+}
+
+//* Receiver端的远程调用者[保留名称,节点不可使用] */
+const RPCCommander = "Commander"
+
+//* 远程调用声明 */
+type RPCS struct {
+	// 参数类型[不含conn与messageId,如 string | int64 | float64 | bool]
+	Args []string
+	// 允许的调用者[空则不限; NeuronId | role:<角色> | RPCCommander]
+	Allow []string
+}
+
+//* 远程调用上下文 */
+type RPCContextS struct {
+	// *websocket.Conn
+	Conn      interface{}
+	MessageId string
+	// 调用者NeuronId[Receiver端为RPCCommander]
+	Caller   string
+	Service  string
+	Function string
+}
+
+//* 已注册的远程调用 */
+type RPCHandlerS struct {
+	Service  string
+	Function string
+	Allow    []string
+	// 请求结构体类型
+	Request reflect.Type
+	Handler reflect.Value
+}
@@ -10,10 +10,103 @@ type autorunS struct {
 	SDExampleSubscribe bool
 }
 
+type commanderParamS struct {
+	AckTimeout    int
+	MaxRetry      int
+	RetryBackoff  int
+	MaxBackoff    int
+	PendingLen    int
+	DeadLetterLen int
+	SuspectMiss   int
+	DeadMiss      int
+	NodeRate      int
+	NodeBurst     int
+	SendBacklog   int
+	QueueLimit    int
+	DedupLen      int
+}
+
+type securityS struct {
+	ActiveKey    string
+	Keys         map[string]string
+	Legacy       bool
+	ReplayWindow int
+	Scopes       map[string][]string
+}
+
+type joinS struct {
+	Open       bool
+	Credential string
+	Approved   map[string]string
+}
+
+type rpcS struct {
+	Strict bool
+	Roles  map[string][]string
+}
+
+type authS struct {
+	Open         bool
+	Clients      map[string]authClientS
+	PublicKey    string
+	PrivateKey   string
+	TokenTTL     int
+	ReplayWindow int
+}
+
+type authClientS struct {
+	Secret string
+	Roles  []string
+}
+
+type reconnectS struct {
+	BaseDelay   int
+	MaxDelay    int
+	Jitter      float64
+	MaxAttempts int
+}
+
+type muxS struct {
+	Window  int
+	Backlog int
+}
+
+type transferParamS struct {
+	Path      string
+	ChunkSize int
+	MaxRetry  int
+	Timeout   int
+	Expire    int
+	MaxSize   int64
+}
+
+type traceS struct {
+	Enable    bool
+	Path      string
+	MaxSize   int64
+	MemoryLen int
+}
+
 type behaviorTreeS struct {
 	ErrorQLen int
 }
 
+type persistenceS struct {
+	Commander       bool
+	BehaviorForest  bool
+	SegmentSize     int
+	CompactInterval int
+	SyncInterval    int
+}
+
+type clusterS struct {
+	Open      bool
+	Store     string
+	Prefix    string
+	LeaseTTL  int
+	Advertise string
+}
+
 type databaseS struct {
 	Open     bool
 	Log      bool
@@ -33,7 +126,9 @@ type redisS struct {
 }
 
 type proxyS struct {
-	ProxyHub  map[string]interface{}
+	ProxyHub map[string]interface{}
+	// 反向隧道允许的内网目标[host:port | *]
+	TunnelAllow []string
 }
 
 type fileS struct {
@@ -52,6 +147,8 @@ type serverS struct {
 	UploadPath string
 	XPoweredBy string
 	ACAO       bool
+	Always200  bool
+	MaxBody    int64
 }
 
 type tlsServerS struct {
@@ -63,6 +160,8 @@ type tlsServerS struct {
 type wsParamS struct {
 	Interval   int
 	BufferSize int
+	// GMessage编码格式[text | bin1,文本格式协商时优先使用text2以携带追踪编号]
+	Codec string
 }
 
 type tcpParamS struct {
@@ -92,6 +191,7 @@ type intervalS struct {
 	SystemInterval    int
 	RetryInterval     int
 	TwoHourInterval   int
+	CallTimeout       int
 }
 
 //* ================================ PUBLIC ================================ */
@@ -105,24 +205,112 @@ type Const struct {
 	RunEnv        int
 	Version       string
 	NeuronId      string
+	NeuronLabels  map[string]string
 	SystemSplit   string
 	CommanderHost string
-	CommanderLog  bool
-	BehaviorTree  behaviorTreeS
-	AutorunConfig autorunS
-	ErrorCode     map[int]string
-	Database      databaseS
-	Redis         redisS
-	File          fileS
-	Proxy         proxyS
-	HTTPRequest   requestS
-	HTTPServer    serverS
-	HTTPS         tlsServerS
-	WSParam       wsParamS
-	TCPParam      tcpParamS
-	UDPParam      udpParamS
-	UartParam     uartParamS
-	Interval      intervalS
+	// Commander故障转移地址[为空则仅使用CommanderHost]
+	CommanderHosts []string
+	CommanderLog   bool
+	/* Receiver重连策略
+		BaseDelay/MaxDelay -> 退避基数/上限毫秒数
+		Jitter -> 抖动比例[0 - 1]
+		MaxAttempts -> 最大连续失败次数[<=0则不限]
+	*/
+	Reconnect reconnectS
+	/* 连接多路复用[Proxy/Mux]
+		Window -> 每个流的初始窗口字节数[两端需一致]
+		Backlog -> 未Accept的流上限
+	*/
+	Mux muxS
+	/* 分块文件传输
+		Path -> 传输根目录[推送目标及拉取来源均限定于此,不可位于静态目录下]
+		ChunkSize -> 分块字节数[超过WSParam.BufferSize/2则取其一半]
+		MaxRetry -> 最大重传轮数
+		Timeout -> 等待对端状态毫秒数
+		Expire -> 未完成传输的保留毫秒数
+		MaxSize -> 单个文件字节数上限[<=0则不限,分块数另限定于1048576]
+	*/
+	TransferParam transferParamS
+	/* Commander投递参数
+		AckTimeout -> 等待ACK毫秒数
+		MaxRetry -> 最大重发次数[超过则进入死信队列]
+		RetryBackoff/MaxBackoff -> 重发退避基数/上限毫秒数
+		PendingLen -> 离线节点暂存队列长度
+		DeadLetterLen -> 死信队列长度
+		SuspectMiss/DeadMiss -> 错过心跳次数[达到则标记为suspect/dead]
+		NodeRate/NodeBurst -> 每个节点每秒发送数及突发数[NodeRate<=0则不限,Urgent不受限]
+		SendBacklog -> 节点发送队列总长度[超出则暂留于CommanderPriority/CommanderQueue]
+		QueueLimit -> Receiver服务任务队列上限[达到则回复繁忙,<=0不限制]
+		DedupLen -> Receiver去重窗口[保留最近已执行的指令编号数]
+	*/
+	CommanderParam commanderParamS
+	/* 指令追踪
+		Enable -> 是否记录追踪[默认关闭,开启后每条指令额外上报!TRACE]
+		Path -> 追踪日志文件[Json行,超过MaxSize字节则轮转为.1]
+		MemoryLen -> 内存中保留的追踪数量
+	*/
+	Trace traceS
+	/* 通信加密[AES-256-GCM信封]
+		ActiveKey -> 发送使用的秘钥编号[Keys中不存在则使用SystemEncrypt]
+		Keys -> 秘钥环[编号 -> Base64(32字节)或口令],轮换时新旧秘钥并存
+		Legacy -> 是否接收SystemEncrypt旧格式[未配置有效ActiveKey时总是接收,否则仅在迁移期间显式开启]
+		ReplayWindow -> 时间戳容差毫秒数[防重放]
+		Scopes -> 秘钥限定节点[编号 -> NeuronId列表],Commander拒绝其他节点使用该秘钥,未列出则不限
+	*/
+	Security       securityS
+	/* 节点握手认证[凭证格式 hmac:<secret> | rsa:<PEM路径>]
+		Open -> Commander是否要求握手
+		Credential -> Receiver凭证[HMAC秘钥或RSA私钥]
+		Approved -> Commander允许注册的节点[NeuronId -> HMAC秘钥或RSA公钥]
+	*/
+	Join           joinS
+	/* 远程调用权限
+		Strict -> 未实现RPCI的服务是否拒绝远程调用
+		Roles -> 节点角色[NeuronId -> 角色],节点标签role仅在通过握手认证后生效
+	*/
+	RPC            rpcS
+	/* HTTP接口认证[Open为false时不校验]
+		Clients -> 客户端[ClientId -> Secret/Roles],Secret同时作为API Key及HMAC秘钥,角色*拥有全部权限
+		PublicKey/PrivateKey -> JWT[RS256]验签公钥/签发私钥PEM路径
+		TokenTTL -> 签发JWT有效毫秒数
+		ReplayWindow -> HMAC签名时间戳容差毫秒数[防重放]
+	*/
+	Auth           authS
+	BehaviorTree   behaviorTreeS
+	/* 持久化配置[/data目录下的预写日志]
+		Commander -> CommanderQueue/CommanderPriority/CommanderReply/CommanderDeadLetter及调度、投递中的指令
+		BehaviorForest -> 行为森林UUIDQ/Trees
+		SegmentSize -> 分段文件字节数
+		CompactInterval -> 压缩间隔毫秒数
+		SyncInterval -> 刷盘[0逐条fsync | >0合并fsync毫秒数 | <0交由操作系统]
+	*/
+	Persistence    persistenceS
+	/* Commander集群[多Commander选主]
+		Store -> 共享存储[redis | memory],memory仅限单进程且须显式配置,Redis不可用则终止启动
+		Prefix -> 共享存储键前缀
+		LeaseTTL -> 主节点租约毫秒数
+		Advertise -> 本Commander对外地址[重定向Receiver,为空则使用CommanderHost]
+	*/
+	Cluster        clusterS
+	AutorunConfig  autorunS
+	ErrorCode      map[int]string
+	Database       databaseS
+	Redis          redisS
+	File           fileS
+	Proxy          proxyS
+	HTTPRequest    requestS
+	/* HTTP服务
+		ACAO -> 是否允许跨域
+		Always200 -> 始终返回HTTP 200[兼容仅解析Code的旧客户端]
+		MaxBody -> 请求体字节数上限[Bind及HMAC认证读取时生效,超出返回207,<=0不限]
+	*/
+	HTTPServer     serverS
+	HTTPS          tlsServerS
+	WSParam        wsParamS
+	TCPParam       tcpParamS
+	UDPParam       udpParamS
+	UartParam      uartParamS
+	Interval       intervalS
 }
 
 //* 构造本体 */
@@ -131,12 +319,91 @@ func (*Const) Ontology() Const {
 		0,
 		"1.4.9",
 		"Neuron",
+		map[string]string{},
 		"___",
 		"ws://127.0.0.1:8800/Commander/Channel",
+		[]string{},
 		false,
+		reconnectS{
+			1000,
+			60000,
+			0.5,
+			0,
+		},
+		muxS{
+			256 << 10,
+			64,
+		},
+		transferParamS{
+			"/data/transfer",
+			256 << 10,
+			3,
+			30000,
+			3600000,
+			1 << 30,
+		},
+		commanderParamS{
+			5000,
+			5,
+			1000,
+			60000,
+			4096,
+			4096,
+			1,
+			3,
+			100,
+			100,
+			4096,
+			256,
+			65536,
+		},
+		traceS{
+			false,
+			"/log/trace.log",
+			64 << 20,
+			4096,
+		},
+		securityS{
+			"",
+			map[string]string{},
+			false,
+			300000,
+			map[string][]string{},
+		},
+		joinS{
+			false,
+			"",
+			map[string]string{},
+		},
+		rpcS{
+			true,
+			map[string][]string{},
+		},
+		authS{
+			false,
+			map[string]authClientS{},
+			"",
+			"",
+			3600000,
+			300000,
+		},
 		behaviorTreeS{
 			512,
 		},
+		persistenceS{
+			false,
+			false,
+			4 << 20,
+			600000,
+			0,
+		},
+		clusterS{
+			false,
+			"redis",
+			"neuron",
+			15000,
+			"",
+		},
 		/* 自启动配置 */
 		autorunS{
 			true,
@@ -178,6 +445,7 @@ func (*Const) Ontology() Const {
 			220: "Null Error",
 			221: "DataType Error",
 			222: "UART Error",
+			223: "Service Busy",
 
 			300: "Database Disconnected",
 			301: "Query Error",
@@ -218,6 +486,7 @@ func (*Const) Ontology() Const {
 				"UDP2TCP":  map[string]interface{}{},
 				"UART2UDP": map[string]interface{}{},
 			},
+			[]string{},
 		},
 		requestS{
 			map[string][]string{
@@ -232,6 +501,9 @@ func (*Const) Ontology() Const {
 			"Neuron",
 			/* 跨域标识 */
 			false,
+			/* 错误码亦返回HTTP 200[兼容旧客户端] */
+			false,
+			32 << 20,
 		},
 		tlsServerS{
 			false,
@@ -241,6 +513,7 @@ func (*Const) Ontology() Const {
 		wsParamS{
 			120000,
 			2 << 20,
+			CodecBinary,
 		},
 		tcpParamS{
 			120000,
@@ -266,6 +539,7 @@ func (*Const) Ontology() Const {
 			60000,
 			5000,
 			7200000,
+			60000,
 		},
 	}
 }
@@ -17,3 +17,105 @@ type WebsocketI interface {
 	// 消息解析模块
 	GMessageHandler(interface{}, interface{})
 }
+
+//* 节点事件接口[NODE_JOIN | NODE_LEAVE | NODE_SUSPECT] */
+type NodeEventI interface {
+	NodeEvent(event string, node NodeS)
+}
+
+//* 远程调用白名单接口[方法名 -> 声明] */
+type RPCI interface {
+	RPC() map[string]RPCS
+}
+
+//* 队列深度接口[心跳上报] */
+type QueueDepthI interface {
+	QueueDepth() map[string]int
+}
+
+//* 任务额度接口[可接受的任务数,<0不限制] */
+type CreditI interface {
+	Credit() int
+}
+
+//* 队列接口[QueueS / PersistQueueS] */
+type QueueI interface {
+	// 入队尾
+	Push(interface{})
+	// 入队首
+	UnShift(interface{})
+	// 出队首
+	Shift() interface{}
+	// 取队尾
+	Pop() interface{}
+	// 出队首(不出队)
+	ShiftPeek() interface{}
+	// 获取长度
+	Len() int
+	// 判断是否为空
+	IsEmpty() bool
+	// 转数组对象
+	ToArrayV() []interface{}
+}
+
+//* 键值容器接口[SyncMapHub / PersistMapS] */
+type MapI interface {
+	// 获取元素
+	Get(string) interface{}
+	// 设置元素
+	Set(string, interface{})
+	// 取出元素
+	Pop(string) interface{}
+	// 删除元素
+	Del(string)
+	// 获取长度
+	Len() int
+	// 迭代器
+	Iterator(func(n int, k string, v interface{}) bool)
+}
+
+//* 持久化接口[PersistQueueS / PersistMapS] */
+type PersistI interface {
+	// 压缩日志
+	Compact() error
+	// 获取最近一次写入错误
+	Err() error
+	// 刷盘[合并刷盘时写入的缓冲]
+	Sync() error
+	// 关闭日志
+	Close() error
+}
+
+//* 集群共享存储接口[RedisS / MemoryStoreS] */
+type StoreI interface {
+	// 抢占租约[键不存在时写入,ttl毫秒]
+	Acquire(key, value string, ttl int) (bool, error)
+	// 续约[值一致时延长]
+	Renew(key, value string, ttl int) (bool, error)
+	// 释放租约[值一致时删除]
+	Release(key, value string) error
+	// 读取租约值[不存在返回空]
+	Get(key string) (string, error)
+	// 列表写入[head -> 队首]
+	ListPush(key string, value []byte, head bool) error
+	// 列表取出[head -> 队首,为空返回nil]
+	ListPop(key string, head bool) ([]byte, error)
+	// 列表读取
+	ListIndex(key string, index int) ([]byte, error)
+	// 列表长度
+	ListLen(key string) (int, error)
+	// 列表全部元素
+	ListRange(key string) ([][]byte, error)
+	// 按前缀列出列表键
+	Keys(prefix string) ([]string, error)
+	// 散列写入
+	HashSet(key, field string, value []byte) error
+	// 散列读取[不存在返回nil]
+	HashGet(key, field string) ([]byte, error)
+	// 散列删除
+	HashDel(key, field string) error
+	// 散列长度
+	HashLen(key string) (int, error)
+	// 散列全部元素
+	HashAll(key string) (map[string][]byte, error)
+}
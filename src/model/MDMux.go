@@ -0,0 +1,544 @@
+/**
+===========================================================================
+ * 连接多路复用
+ * Stream multiplexing over a single connection
+ * 帧头[12字节] -> version(1) type(1) flags(2) streamId(4) length(4)
+ * type -> DATA(length为负载长度) | WINDOW(length为窗口增量) | GOAWAY
+ * flags -> SYN(打开) | ACK(确认打开) | FIN(半关闭) | RST(重置)
+ * 客户端流编号为奇数,服务端为偶数,对端以本端奇偶打开流则重置
+ * 用于独立连接[*websocket.Conn | net.Conn],两端均须使用MuxS
+===========================================================================
+*/
+package model
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	muxVersion    = 0
+	muxHeaderLen  = 12
+	muxMaxPayload = 16 << 10
+)
+
+//* 帧类型 */
+const (
+	muxTypeData   = 0
+	muxTypeWindow = 1
+	muxTypeGoAway = 2
+)
+
+//* 帧标志 */
+const (
+	muxFlagSYN = 1 << iota
+	muxFlagACK
+	muxFlagFIN
+	muxFlagRST
+)
+
+var (
+	ErrMuxClosed   = fmt.Errorf("mux -> Closed")
+	ErrMuxReset    = fmt.Errorf("mux -> Stream Reset")
+	ErrMuxTimeout  = fmt.Errorf("mux -> Timeout")
+	ErrMuxProtocol = fmt.Errorf("mux -> Protocol Error")
+)
+
+//* 多路复用会话[实现net.Listener] */
+type MuxS struct {
+	// 每个流的初始窗口字节数[两端需一致]
+	Window uint32
+	// 未Accept的流上限
+	Backlog int
+
+	conn      net.Conn
+	isClient  bool
+	nextId    uint32
+	streams   map[uint32]*MuxStreamS
+	lock      *sync.Mutex
+	writeLock *sync.Mutex
+	acceptC   chan *MuxStreamS
+	closeC    chan bool
+	closeOnce *sync.Once
+	err       error
+}
+
+//* 新建会话并开始读取[isClient -> 流编号奇偶] */
+func (session *MuxS) New(conn net.Conn, isClient bool, window int, backlog int) *MuxS {
+	if window <= 0 {
+		window = 256 << 10
+	}
+	if backlog <= 0 {
+		backlog = 64
+	}
+	nextId := uint32(2)
+	if isClient {
+		nextId = 1
+	}
+	mux := &MuxS{
+		Window:    uint32(window),
+		Backlog:   backlog,
+		conn:      conn,
+		isClient:  isClient,
+		nextId:    nextId,
+		streams:   make(map[uint32]*MuxStreamS),
+		lock:      new(sync.Mutex),
+		writeLock: new(sync.Mutex),
+		acceptC:   make(chan *MuxStreamS, backlog),
+		closeC:    make(chan bool),
+		closeOnce: new(sync.Once),
+	}
+	go mux.readLoop()
+	return mux
+}
+
+//* 写入一帧[单次Write,websocket下一帧对应一条消息] */
+func (session *MuxS) writeFrame(frameType byte, flags uint16, id uint32, length uint32, body []byte) error {
+	buf := make([]byte, muxHeaderLen+len(body))
+	buf[0] = muxVersion
+	buf[1] = frameType
+	binary.BigEndian.PutUint16(buf[2:4], flags)
+	binary.BigEndian.PutUint32(buf[4:8], id)
+	binary.BigEndian.PutUint32(buf[8:12], length)
+	copy(buf[muxHeaderLen:], body)
+	defer session.writeLock.Unlock()
+	session.writeLock.Lock()
+	select {
+	case <-session.closeC:
+		return ErrMuxClosed
+	default:
+	}
+	_, err := session.conn.Write(buf)
+	if err != nil {
+		go session.close(err)
+	}
+	return err
+}
+
+//* 读取循环 */
+func (session *MuxS) readLoop() {
+	header := make([]byte, muxHeaderLen)
+	for {
+		if _, err := io.ReadFull(session.conn, header); err != nil {
+			session.close(err)
+			return
+		}
+		if header[0] != muxVersion {
+			session.close(ErrMuxProtocol)
+			return
+		}
+		frameType := header[1]
+		flags := binary.BigEndian.Uint16(header[2:4])
+		id := binary.BigEndian.Uint32(header[4:8])
+		length := binary.BigEndian.Uint32(header[8:12])
+		var body []byte
+		if frameType == muxTypeData && length > 0 {
+			if length > session.Window {
+				session.close(ErrMuxProtocol)
+				return
+			}
+			body = make([]byte, length)
+			if _, err := io.ReadFull(session.conn, body); err != nil {
+				session.close(err)
+				return
+			}
+		}
+		switch frameType {
+		case muxTypeGoAway:
+			session.close(ErrMuxClosed)
+			return
+		case muxTypeData, muxTypeWindow:
+			session.handleFrame(frameType, flags, id, length, body)
+		default:
+			session.close(ErrMuxProtocol)
+			return
+		}
+	}
+}
+
+//* 处理流帧 */
+func (session *MuxS) handleFrame(frameType byte, flags uint16, id uint32, length uint32, body []byte) {
+	session.lock.Lock()
+	stream, found := session.streams[id]
+	if !found && flags&muxFlagSYN != 0 {
+		// 对端只能使用其自身奇偶的流编号
+		if id == 0 || (id%2 == 1) != !session.isClient {
+			session.lock.Unlock()
+			session.writeFrame(muxTypeWindow, muxFlagRST, id, 0, nil)
+			return
+		}
+		stream = session.newStream(id)
+		select {
+		case session.acceptC <- stream:
+			session.streams[id] = stream
+			found = true
+		default:
+			// 积压已满,拒绝打开
+			session.lock.Unlock()
+			session.writeFrame(muxTypeWindow, muxFlagRST, id, 0, nil)
+			return
+		}
+	}
+	session.lock.Unlock()
+	// 已关闭的流,丢弃迟到的帧
+	if !found {
+		return
+	}
+	if flags&muxFlagRST != 0 {
+		stream.reset()
+		return
+	}
+	if frameType == muxTypeData {
+		if !stream.receive(body) {
+			stream.Reset()
+			return
+		}
+	} else if length > 0 {
+		stream.grow(length)
+	}
+	if flags&muxFlagFIN != 0 {
+		stream.remoteClose()
+	}
+}
+
+func (session *MuxS) newStream(id uint32) *MuxStreamS {
+	return &MuxStreamS{
+		Id:         id,
+		session:    session,
+		lock:       new(sync.Mutex),
+		recvC:      make(chan bool, 1),
+		sendC:      make(chan bool, 1),
+		recvWindow: session.Window,
+		sendWindow: session.Window,
+	}
+}
+
+//* 移除流 */
+func (session *MuxS) remove(id uint32) {
+	defer session.lock.Unlock()
+	session.lock.Lock()
+	delete(session.streams, id)
+}
+
+//* 关闭会话并重置全部流 */
+func (session *MuxS) close(err error) {
+	session.closeOnce.Do(func() {
+		session.lock.Lock()
+		session.err = err
+		streams := session.streams
+		session.streams = make(map[uint32]*MuxStreamS)
+		session.lock.Unlock()
+		close(session.closeC)
+		session.conn.Close()
+		for _, v := range streams {
+			v.reset()
+		}
+	})
+}
+
+//* 打开新流 */
+func (session *MuxS) Open() (*MuxStreamS, error) {
+	session.lock.Lock()
+	select {
+	case <-session.closeC:
+		session.lock.Unlock()
+		return nil, ErrMuxClosed
+	default:
+	}
+	id := session.nextId
+	session.nextId += 2
+	stream := session.newStream(id)
+	session.streams[id] = stream
+	session.lock.Unlock()
+	if err := session.writeFrame(muxTypeWindow, muxFlagSYN, id, 0, nil); err != nil {
+		session.remove(id)
+		return nil, err
+	}
+	return stream, nil
+}
+
+//* 等待对端打开的流 */
+func (session *MuxS) AcceptStream() (*MuxStreamS, error) {
+	select {
+	case stream := <-session.acceptC:
+		if err := session.writeFrame(muxTypeWindow, muxFlagACK, stream.Id, 0, nil); err != nil {
+			return nil, err
+		}
+		return stream, nil
+	case <-session.closeC:
+		return nil, ErrMuxClosed
+	}
+}
+
+//* 等待对端打开的流[net.Listener] */
+func (session *MuxS) Accept() (net.Conn, error) {
+	return session.AcceptStream()
+}
+
+//* 通知对端并关闭会话 */
+func (session *MuxS) Close() error {
+	session.writeFrame(muxTypeGoAway, 0, 0, 0, nil)
+	session.close(ErrMuxClosed)
+	return nil
+}
+
+//* 本地地址[net.Listener] */
+func (session *MuxS) Addr() net.Addr {
+	return session.conn.LocalAddr()
+}
+
+//* 会话关闭通道 */
+func (session *MuxS) Done() <-chan bool {
+	return session.closeC
+}
+
+//* 会话关闭原因 */
+func (session *MuxS) Err() error {
+	defer session.lock.Unlock()
+	session.lock.Lock()
+	return session.err
+}
+
+//* 当前流数量 */
+func (session *MuxS) NumStreams() int {
+	defer session.lock.Unlock()
+	session.lock.Lock()
+	return len(session.streams)
+}
+
+//* 复用流[实现net.Conn] */
+type MuxStreamS struct {
+	Id      uint32
+	session *MuxS
+	lock    *sync.Mutex
+	recvC   chan bool
+	sendC   chan bool
+
+	recvBuf bytes.Buffer
+	// 对端剩余可发送字节数
+	recvWindow uint32
+	// 已读取未通告的字节数
+	consumed   uint32
+	sendWindow uint32
+
+	localFin  bool
+	remoteFin bool
+	isReset   bool
+
+	readDeadline  time.Time
+	writeDeadline time.Time
+}
+
+//* 非阻塞通知 */
+func muxNotify(c chan bool) {
+	select {
+	case c <- true:
+	default:
+	}
+}
+
+//* 等待通知或超时 */
+func (stream *MuxStreamS) wait(c chan bool, deadline time.Time) error {
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		d := time.Until(deadline)
+		if d <= 0 {
+			return ErrMuxTimeout
+		}
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-c:
+		return nil
+	case <-stream.session.closeC:
+		return nil
+	case <-timeout:
+		return ErrMuxTimeout
+	}
+}
+
+//* 写入接收缓冲[超出窗口返回false] */
+func (stream *MuxStreamS) receive(body []byte) bool {
+	defer muxNotify(stream.recvC)
+	defer stream.lock.Unlock()
+	stream.lock.Lock()
+	if uint32(len(body)) > stream.recvWindow {
+		return false
+	}
+	stream.recvWindow -= uint32(len(body))
+	stream.recvBuf.Write(body)
+	return true
+}
+
+//* 增加发送窗口 */
+func (stream *MuxStreamS) grow(delta uint32) {
+	defer muxNotify(stream.sendC)
+	defer stream.lock.Unlock()
+	stream.lock.Lock()
+	stream.sendWindow += delta
+}
+
+//* 对端半关闭 */
+func (stream *MuxStreamS) remoteClose() {
+	stream.lock.Lock()
+	stream.remoteFin = true
+	done := stream.localFin
+	stream.lock.Unlock()
+	muxNotify(stream.recvC)
+	if done {
+		stream.session.remove(stream.Id)
+	}
+}
+
+//* 标记重置 */
+func (stream *MuxStreamS) reset() {
+	stream.lock.Lock()
+	stream.isReset = true
+	stream.lock.Unlock()
+	muxNotify(stream.recvC)
+	muxNotify(stream.sendC)
+	stream.session.remove(stream.Id)
+}
+
+//* 读取数据 */
+func (stream *MuxStreamS) Read(b []byte) (int, error) {
+	for {
+		stream.lock.Lock()
+		if stream.recvBuf.Len() > 0 {
+			n, _ := stream.recvBuf.Read(b)
+			// 已读取超过半个窗口时通告对端
+			stream.consumed += uint32(n)
+			delta := uint32(0)
+			if stream.consumed >= stream.session.Window/2 {
+				delta = stream.consumed
+				stream.consumed = 0
+				stream.recvWindow += delta
+			}
+			stream.lock.Unlock()
+			if delta > 0 {
+				stream.session.writeFrame(muxTypeWindow, 0, stream.Id, delta, nil)
+			}
+			return n, nil
+		}
+		if stream.isReset {
+			stream.lock.Unlock()
+			return 0, ErrMuxReset
+		}
+		if stream.remoteFin {
+			stream.lock.Unlock()
+			return 0, io.EOF
+		}
+		deadline := stream.readDeadline
+		stream.lock.Unlock()
+		select {
+		case <-stream.session.closeC:
+			return 0, ErrMuxClosed
+		default:
+		}
+		if err := stream.wait(stream.recvC, deadline); err != nil {
+			return 0, err
+		}
+	}
+}
+
+//* 写入数据[受发送窗口限制] */
+func (stream *MuxStreamS) Write(b []byte) (int, error) {
+	total := 0
+	for total < len(b) {
+		stream.lock.Lock()
+		if stream.isReset {
+			stream.lock.Unlock()
+			return total, ErrMuxReset
+		}
+		if stream.localFin {
+			stream.lock.Unlock()
+			return total, ErrMuxClosed
+		}
+		if stream.sendWindow == 0 {
+			deadline := stream.writeDeadline
+			stream.lock.Unlock()
+			select {
+			case <-stream.session.closeC:
+				return total, ErrMuxClosed
+			default:
+			}
+			if err := stream.wait(stream.sendC, deadline); err != nil {
+				return total, err
+			}
+			continue
+		}
+		n := uint32(len(b) - total)
+		if n > stream.sendWindow {
+			n = stream.sendWindow
+		}
+		if n > muxMaxPayload {
+			n = muxMaxPayload
+		}
+		stream.sendWindow -= n
+		stream.lock.Unlock()
+		if err := stream.session.writeFrame(muxTypeData, 0, stream.Id, n, b[total:total+int(n)]); err != nil {
+			return total, err
+		}
+		total += int(n)
+	}
+	return total, nil
+}
+
+//* 半关闭[发送FIN,仍可读取剩余数据] */
+func (stream *MuxStreamS) Close() error {
+	stream.lock.Lock()
+	if stream.localFin || stream.isReset {
+		stream.lock.Unlock()
+		return nil
+	}
+	stream.localFin = true
+	done := stream.remoteFin
+	stream.lock.Unlock()
+	muxNotify(stream.sendC)
+	if done {
+		stream.session.remove(stream.Id)
+	}
+	return stream.session.writeFrame(muxTypeData, muxFlagFIN, stream.Id, 0, nil)
+}
+
+//* 重置流[立即终止双向传输] */
+func (stream *MuxStreamS) Reset() error {
+	stream.reset()
+	return stream.session.writeFrame(muxTypeWindow, muxFlagRST, stream.Id, 0, nil)
+}
+
+func (stream *MuxStreamS) LocalAddr() net.Addr {
+	return stream.session.conn.LocalAddr()
+}
+
+func (stream *MuxStreamS) RemoteAddr() net.Addr {
+	return stream.session.conn.RemoteAddr()
+}
+
+func (stream *MuxStreamS) SetDeadline(t time.Time) error {
+	stream.SetReadDeadline(t)
+	return stream.SetWriteDeadline(t)
+}
+
+func (stream *MuxStreamS) SetReadDeadline(t time.Time) error {
+	defer muxNotify(stream.recvC)
+	defer stream.lock.Unlock()
+	stream.lock.Lock()
+	stream.readDeadline = t
+	return nil
+}
+
+func (stream *MuxStreamS) SetWriteDeadline(t time.Time) error {
+	defer muxNotify(stream.sendC)
+	defer stream.lock.Unlock()
+	stream.lock.Lock()
+	stream.writeDeadline = t
+	return nil
+}
@@ -0,0 +1,198 @@
+package model
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+//* 建立回环TCP连接对 */
+func muxConnPair(t *testing.T) (net.Conn, net.Conn) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	acceptC := make(chan net.Conn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			acceptC <- nil
+			return
+		}
+		acceptC <- conn
+	}()
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := <-acceptC
+	if server == nil {
+		t.Fatal("accept failed")
+	}
+	return client, server
+}
+
+//* 建立客户端及服务端会话 */
+func muxSessionPair(t *testing.T, window int) (*MuxS, *MuxS) {
+	client, server := muxConnPair(t)
+	return new(MuxS).New(client, true, window, 0), new(MuxS).New(server, false, window, 0)
+}
+
+//* 打开流并在对端接受 */
+func muxStreamPair(t *testing.T, client, server *MuxS) (*MuxStreamS, *MuxStreamS) {
+	local, err := client.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := server.AcceptStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return local, remote
+}
+
+func TestMuxWindow(t *testing.T) {
+	const window = 1024
+	client, server := muxSessionPair(t, window)
+	defer client.Close()
+	defer server.Close()
+	local, remote := muxStreamPair(t, client, server)
+	payload := bytes.Repeat([]byte("neuron"), window)
+	// 对端未读取时最多写入一个窗口
+	local.SetWriteDeadline(time.Now().Add(200 * time.Millisecond))
+	n, err := local.Write(payload)
+	if err != ErrMuxTimeout {
+		t.Fatalf("expected ErrMuxTimeout, got %v", err)
+	}
+	if n != window {
+		t.Fatalf("expected %d bytes within window, wrote %d", window, n)
+	}
+	// 读取后窗口恢复,剩余数据可写入
+	local.SetWriteDeadline(time.Time{})
+	done := make(chan error, 1)
+	go func() {
+		_, err := local.Write(payload[n:])
+		if err == nil {
+			err = local.Close()
+		}
+		done <- err
+	}()
+	received, err := ioutil.ReadAll(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(received, payload) {
+		t.Fatalf("expected %d bytes, received %d", len(payload), len(received))
+	}
+}
+
+func TestMuxFin(t *testing.T) {
+	client, server := muxSessionPair(t, 0)
+	defer client.Close()
+	defer server.Close()
+	local, remote := muxStreamPair(t, client, server)
+	if _, err := local.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	if err := local.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// 半关闭后写入失败
+	if _, err := local.Write([]byte("late")); err != ErrMuxClosed {
+		t.Fatalf("expected ErrMuxClosed, got %v", err)
+	}
+	// 对端读完剩余数据后收到EOF
+	received, err := ioutil.ReadAll(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(received) != "ping" {
+		t.Fatalf("expected ping, received %q", received)
+	}
+	// 对端仍可回写
+	if _, err := remote.Write([]byte("pong")); err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Close(); err != nil {
+		t.Fatal(err)
+	}
+	received, err = ioutil.ReadAll(local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(received) != "pong" {
+		t.Fatalf("expected pong, received %q", received)
+	}
+	// 双向关闭后移除流
+	deadline := time.Now().Add(time.Second)
+	for client.NumStreams()+server.NumStreams() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := client.NumStreams() + server.NumStreams(); n != 0 {
+		t.Fatalf("expected streams removed, %d left", n)
+	}
+}
+
+func TestMuxRst(t *testing.T) {
+	client, server := muxSessionPair(t, 0)
+	defer client.Close()
+	defer server.Close()
+	local, remote := muxStreamPair(t, client, server)
+	readC := make(chan error, 1)
+	go func() {
+		_, err := remote.Read(make([]byte, 16))
+		readC <- err
+	}()
+	if err := local.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-readC:
+		if err != ErrMuxReset {
+			t.Fatalf("expected ErrMuxReset, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("read not interrupted by RST")
+	}
+	if _, err := remote.Write([]byte("data")); err != ErrMuxReset {
+		t.Fatalf("expected ErrMuxReset on remote write, got %v", err)
+	}
+	if _, err := local.Write([]byte("data")); err != ErrMuxReset {
+		t.Fatalf("expected ErrMuxReset on local write, got %v", err)
+	}
+}
+
+func TestMuxSynParity(t *testing.T) {
+	client, server := muxConnPair(t)
+	session := new(MuxS).New(server, false, 0, 0)
+	defer session.Close()
+	defer client.Close()
+	// 客户端以服务端的偶数编号打开流
+	header := make([]byte, muxHeaderLen)
+	header[0] = muxVersion
+	header[1] = muxTypeWindow
+	binary.BigEndian.PutUint16(header[2:4], muxFlagSYN)
+	binary.BigEndian.PutUint32(header[4:8], 2)
+	if _, err := client.Write(header); err != nil {
+		t.Fatal(err)
+	}
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	reply := make([]byte, muxHeaderLen)
+	if _, err := io.ReadFull(client, reply); err != nil {
+		t.Fatal(err)
+	}
+	if flags := binary.BigEndian.Uint16(reply[2:4]); flags&muxFlagRST == 0 || binary.BigEndian.Uint32(reply[4:8]) != 2 {
+		t.Fatalf("expected RST for stream 2, got flags %d id %d", flags, binary.BigEndian.Uint32(reply[4:8]))
+	}
+	if n := session.NumStreams(); n != 0 {
+		t.Fatalf("expected no stream accepted, got %d", n)
+	}
+}
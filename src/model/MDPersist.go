@@ -0,0 +1,643 @@
+/**
+===========================================================================
+ * 预写日志持久化容器
+ * Write-ahead-log backed containers
+ * 记录格式 -> [len uint32][crc32 uint32][op byte][payload]
+ * 分段文件 -> dir/00000001.wal ... 超过segmentSize则滚动
+ * 刷盘 -> syncInterval=0逐条fsync | >0按毫秒合并fsync | <0交由操作系统
+ * 无法解码的记录以PersistLostS占位,保持重放后的队列位置与日志一致
+===========================================================================
+*/
+package model
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//* ================================ DEFINE ================================ */
+
+const (
+	walOpReset   byte = 0
+	walOpPush    byte = 1
+	walOpUnShift byte = 2
+	walOpShift   byte = 3
+	walOpPop     byte = 4
+	walOpSet     byte = 5
+	walOpDel     byte = 6
+)
+
+//* 持久化编解码 */
+type PersistCodecS struct {
+	Encode func(v interface{}) ([]byte, error)
+	Decode func(b []byte) (interface{}, error)
+}
+
+//* 无法编解码的记录占位[消费方按类型断言跳过] */
+type PersistLostS struct {
+	Payload []byte
+}
+
+//* 预写日志 */
+type walS struct {
+	dir          string
+	segmentSize  int64
+	syncInterval time.Duration
+	segments     []int
+	file         *os.File
+	size         int64
+	// 合并刷盘
+	mutex sync.Mutex
+	dirty bool
+	timer *time.Timer
+}
+
+//* 持久化队列 */
+type PersistQueueS struct {
+	queue *QueueS
+	wal   *walS
+	codec PersistCodecS
+	lock  *sync.Mutex
+	err   error
+}
+
+//* 持久化map容器 */
+type PersistMapS struct {
+	hub   *SyncMapHub
+	wal   *walS
+	codec PersistCodecS
+	lock  *sync.Mutex
+	err   error
+}
+
+//* ================================ WAL ================================ */
+
+//* 打开预写日志目录 */
+func openWAL(dir string, segmentSize int, syncInterval int) (*walS, error) {
+	if err := os.MkdirAll(dir, 0766); err != nil {
+		return nil, err
+	}
+	names, err := filepath.Glob(filepath.Join(dir, "*.wal"))
+	if err != nil {
+		return nil, err
+	}
+	wal := &walS{dir: dir, segmentSize: int64(segmentSize), syncInterval: time.Duration(syncInterval) * time.Millisecond}
+	for _, v := range names {
+		seq, err := strconv.Atoi(strings.TrimSuffix(filepath.Base(v), ".wal"))
+		if err != nil {
+			continue
+		}
+		wal.segments = append(wal.segments, seq)
+	}
+	sort.Ints(wal.segments)
+	return wal, nil
+}
+
+//* 分段文件路径 */
+func (wal *walS) segmentPath(seq int) string {
+	return filepath.Join(wal.dir, fmt.Sprintf("%08d.wal", seq))
+}
+
+//* 按顺序重放全部分段[尾部损坏的记录将被截断] */
+func (wal *walS) replay(apply func(op byte, payload []byte)) error {
+	for k, seq := range wal.segments {
+		file, err := os.Open(wal.segmentPath(seq))
+		if err != nil {
+			return err
+		}
+		reader := bufio.NewReader(file)
+		var offset int64
+		for {
+			op, payload, n, err := readRecord(reader)
+			if err != nil {
+				break
+			}
+			apply(op, payload)
+			offset += n
+		}
+		file.Close()
+		// 仅最后一个分段允许截断
+		if k == len(wal.segments)-1 {
+			if err := os.Truncate(wal.segmentPath(seq), offset); err != nil {
+				return err
+			}
+		}
+	}
+	// 打开写入分段
+	if len(wal.segments) == 0 {
+		wal.segments = append(wal.segments, 1)
+	}
+	return wal.openSegment(wal.segments[len(wal.segments)-1])
+}
+
+//* 打开分段用于追加 */
+func (wal *walS) openSegment(seq int) error {
+	file, err := os.OpenFile(wal.segmentPath(seq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0766)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	wal.file = file
+	wal.size = info.Size()
+	return nil
+}
+
+//* 追加记录[超过分段大小则滚动] */
+func (wal *walS) append(op byte, payload []byte) error {
+	defer wal.mutex.Unlock()
+	wal.mutex.Lock()
+	if wal.file == nil {
+		return fmt.Errorf("wal[%v] -> Closed", wal.dir)
+	}
+	n, err := wal.file.Write(encodeRecord(op, payload))
+	wal.size += int64(n)
+	if err != nil {
+		return err
+	}
+	if err := wal.sync(); err != nil {
+		return err
+	}
+	if wal.size < wal.segmentSize {
+		return nil
+	}
+	// 滚动前刷盘
+	if wal.dirty {
+		wal.file.Sync()
+		wal.dirty = false
+	}
+	seq := wal.segments[len(wal.segments)-1] + 1
+	wal.file.Close()
+	wal.segments = append(wal.segments, seq)
+	return wal.openSegment(seq)
+}
+
+//* 按syncInterval刷盘[调用方持有mutex] */
+func (wal *walS) sync() error {
+	switch {
+	case wal.syncInterval == 0:
+		return wal.file.Sync()
+	case wal.syncInterval > 0:
+		wal.dirty = true
+		if wal.timer == nil {
+			wal.timer = time.AfterFunc(wal.syncInterval, wal.flush)
+		}
+	}
+	return nil
+}
+
+//* 合并刷盘 */
+func (wal *walS) flush() {
+	defer wal.mutex.Unlock()
+	wal.mutex.Lock()
+	wal.timer = nil
+	if wal.file != nil && wal.dirty {
+		wal.file.Sync()
+	}
+	wal.dirty = false
+}
+
+//* 立即刷盘[取消合并刷盘定时] */
+func (wal *walS) flushNow() error {
+	defer wal.mutex.Unlock()
+	wal.mutex.Lock()
+	if wal.timer != nil {
+		wal.timer.Stop()
+		wal.timer = nil
+	}
+	if wal.file == nil || !wal.dirty {
+		return nil
+	}
+	wal.dirty = false
+	return wal.file.Sync()
+}
+
+//* 以快照重写日志并删除旧分段 */
+func (wal *walS) rewrite(snapshot func(write func(op byte, payload []byte) error) error) error {
+	defer wal.mutex.Unlock()
+	wal.mutex.Lock()
+	if wal.file == nil {
+		return fmt.Errorf("wal[%v] -> Closed", wal.dir)
+	}
+	seq := wal.segments[len(wal.segments)-1] + 1
+	tmpPath := wal.segmentPath(seq) + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0766)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	// 快照以RESET开头,旧分段残留时重放结果不变
+	err = snapshot(func(op byte, payload []byte) error {
+		_, err := writer.Write(encodeRecord(op, payload))
+		return err
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	file.Close()
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, wal.segmentPath(seq)); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	// 切换写入分段并清理旧分段
+	wal.file.Close()
+	olds := wal.segments
+	wal.segments = []int{seq}
+	for _, v := range olds {
+		os.Remove(wal.segmentPath(v))
+	}
+	return wal.openSegment(seq)
+}
+
+//* 关闭日志 */
+func (wal *walS) close() error {
+	defer wal.mutex.Unlock()
+	wal.mutex.Lock()
+	if wal.timer != nil {
+		wal.timer.Stop()
+		wal.timer = nil
+	}
+	if wal.file == nil {
+		return nil
+	}
+	wal.dirty = false
+	wal.file.Sync()
+	err := wal.file.Close()
+	wal.file = nil
+	return err
+}
+
+//* 编码记录 */
+func encodeRecord(op byte, payload []byte) []byte {
+	record := make([]byte, 9+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(1+len(payload)))
+	record[8] = op
+	copy(record[9:], payload)
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(record[8:]))
+	return record
+}
+
+//* 读取记录[返回读取字节数] */
+func readRecord(reader io.Reader) (byte, []byte, int64, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return 0, nil, 0, err
+	}
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length == 0 || length > 1<<30 {
+		return 0, nil, 0, fmt.Errorf("readRecord -> Length Error")
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return 0, nil, 0, err
+	}
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(header[4:8]) {
+		return 0, nil, 0, fmt.Errorf("readRecord -> CRC Error")
+	}
+	return body[0], body[1:], int64(8 + length), nil
+}
+
+//* 编码键值记录 */
+func encodeKV(k string, v []byte) []byte {
+	buf := make([]byte, binary.MaxVarintLen64+len(k)+len(v))
+	n := binary.PutUvarint(buf, uint64(len(k)))
+	n += copy(buf[n:], k)
+	n += copy(buf[n:], v)
+	return buf[:n]
+}
+
+//* 解码键值记录 */
+func decodeKV(payload []byte) (string, []byte, bool) {
+	length, n := binary.Uvarint(payload)
+	if n <= 0 || uint64(len(payload)-n) < length {
+		return "", nil, false
+	}
+	return string(payload[n : n+int(length)]), payload[n+int(length):], true
+}
+
+//* ================================ QUEUE ================================ */
+
+//* 新建持久化队列[重放已有日志] */
+func (pq *PersistQueueS) New(dir string, segmentSize int, syncInterval int, codec PersistCodecS, maxLen ...int) (*PersistQueueS, error) {
+	wal, err := openWAL(dir, segmentSize, syncInterval)
+	if err != nil {
+		return nil, err
+	}
+	queue := &PersistQueueS{
+		queue: new(QueueS).New(maxLen...),
+		wal:   wal,
+		codec: codec,
+		lock:  new(sync.Mutex),
+	}
+	lost := 0
+	err = wal.replay(func(op byte, payload []byte) {
+		switch op {
+		case walOpReset:
+			queue.queue.Renew()
+		case walOpPush, walOpUnShift:
+			// 解码失败则占位,后续Shift/Pop记录仍作用于正确的元素
+			v, err := codec.Decode(payload)
+			if err != nil {
+				lost++
+				v = PersistLostS{Payload: payload}
+			}
+			if op == walOpPush {
+				queue.queue.Push(v)
+			} else {
+				queue.queue.UnShift(v)
+			}
+		case walOpShift:
+			queue.queue.Shift()
+		case walOpPop:
+			queue.queue.Pop()
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if lost > 0 {
+		queue.err = fmt.Errorf("replay[%v] -> %d Records Undecodable", dir, lost)
+	}
+	return queue, nil
+}
+
+//* 写入日志[失败仅记录错误,内存队列继续可用] */
+func (pq *PersistQueueS) log(op byte, v interface{}) {
+	var payload []byte
+	if v != nil {
+		// 编码失败仍写入空记录占位
+		b, err := pq.codec.Encode(v)
+		if err != nil {
+			pq.err = err
+		}
+		payload = b
+	}
+	if err := pq.wal.append(op, payload); err != nil {
+		pq.err = err
+	}
+}
+
+//* 入队尾 */
+func (pq *PersistQueueS) Push(value interface{}) {
+	if value == nil {
+		return
+	}
+	defer pq.lock.Unlock()
+	pq.lock.Lock()
+	pq.queue.Push(value)
+	pq.log(walOpPush, value)
+}
+
+//* 入队首 */
+func (pq *PersistQueueS) UnShift(value interface{}) {
+	if value == nil {
+		return
+	}
+	defer pq.lock.Unlock()
+	pq.lock.Lock()
+	pq.queue.UnShift(value)
+	pq.log(walOpUnShift, value)
+}
+
+//* 出队首 */
+func (pq *PersistQueueS) Shift() interface{} {
+	defer pq.lock.Unlock()
+	pq.lock.Lock()
+	v := pq.queue.Shift()
+	if v != nil {
+		pq.log(walOpShift, nil)
+	}
+	return v
+}
+
+//* 取队尾 */
+func (pq *PersistQueueS) Pop() interface{} {
+	defer pq.lock.Unlock()
+	pq.lock.Lock()
+	v := pq.queue.Pop()
+	if v != nil {
+		pq.log(walOpPop, nil)
+	}
+	return v
+}
+
+//* 出队首(不出队) */
+func (pq *PersistQueueS) ShiftPeek() interface{} {
+	return pq.queue.ShiftPeek()
+}
+
+//* 获取队列长度 */
+func (pq *PersistQueueS) Len() int {
+	return pq.queue.Len()
+}
+
+//* 判断队列是否为空 */
+func (pq *PersistQueueS) IsEmpty() bool {
+	return pq.queue.IsEmpty()
+}
+
+//* 队列转数组对象 */
+func (pq *PersistQueueS) ToArrayV() []interface{} {
+	return pq.queue.ToArrayV()
+}
+
+//* 压缩日志[以当前内容重写] */
+func (pq *PersistQueueS) Compact() error {
+	defer pq.lock.Unlock()
+	pq.lock.Lock()
+	return pq.wal.rewrite(func(write func(op byte, payload []byte) error) error {
+		if err := write(walOpReset, nil); err != nil {
+			return err
+		}
+		for _, v := range pq.queue.ToArrayV() {
+			// 编码失败仍写入占位记录
+			b, err := pq.codec.Encode(v)
+			if lostV, found := v.(PersistLostS); found {
+				b = lostV.Payload
+			} else if err != nil {
+				b = nil
+			}
+			if err := write(walOpPush, b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+//* 获取并清除最近一次写入错误 */
+func (pq *PersistQueueS) Err() error {
+	defer pq.lock.Unlock()
+	pq.lock.Lock()
+	err := pq.err
+	pq.err = nil
+	return err
+}
+
+//* 刷盘 */
+func (pq *PersistQueueS) Sync() error {
+	return pq.wal.flushNow()
+}
+
+//* 关闭日志 */
+func (pq *PersistQueueS) Close() error {
+	defer pq.lock.Unlock()
+	pq.lock.Lock()
+	return pq.wal.close()
+}
+
+//* ================================ MAP ================================ */
+
+//* 新建持久化map容器[重放已有日志] */
+func (pm *PersistMapS) New(dir string, segmentSize int, syncInterval int, codec PersistCodecS, tags ...string) (*PersistMapS, error) {
+	wal, err := openWAL(dir, segmentSize, syncInterval)
+	if err != nil {
+		return nil, err
+	}
+	hub := new(SyncMapHub)
+	hub.Init(tags...)
+	persistMap := &PersistMapS{
+		hub:   hub,
+		wal:   wal,
+		codec: codec,
+		lock:  new(sync.Mutex),
+	}
+	err = wal.replay(func(op byte, payload []byte) {
+		switch op {
+		case walOpReset:
+			hub.Init(tags...)
+		case walOpSet:
+			k, b, ok := decodeKV(payload)
+			if !ok {
+				return
+			}
+			// 解码失败则不保留旧值
+			v, err := codec.Decode(b)
+			if err != nil {
+				hub.Del(k)
+				return
+			}
+			hub.Set(k, v)
+		case walOpDel:
+			hub.Del(string(payload))
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return persistMap, nil
+}
+
+//* 获取元素 */
+func (pm *PersistMapS) Get(k string) interface{} {
+	return pm.hub.Get(k)
+}
+
+//* 设置元素 */
+func (pm *PersistMapS) Set(k string, v interface{}) {
+	defer pm.lock.Unlock()
+	pm.lock.Lock()
+	pm.hub.Set(k, v)
+	b, err := pm.codec.Encode(v)
+	if err != nil {
+		// 编码失败则记录删除,避免重放时恢复旧值
+		pm.err = err
+		if err := pm.wal.append(walOpDel, []byte(k)); err != nil {
+			pm.err = err
+		}
+		return
+	}
+	if err := pm.wal.append(walOpSet, encodeKV(k, b)); err != nil {
+		pm.err = err
+	}
+}
+
+//* 取出元素 */
+func (pm *PersistMapS) Pop(k string) interface{} {
+	defer pm.lock.Unlock()
+	pm.lock.Lock()
+	v := pm.hub.Pop(k)
+	if v != nil {
+		if err := pm.wal.append(walOpDel, []byte(k)); err != nil {
+			pm.err = err
+		}
+	}
+	return v
+}
+
+//* 删除元素 */
+func (pm *PersistMapS) Del(k string) {
+	pm.Pop(k)
+}
+
+//* 返回长度 */
+func (pm *PersistMapS) Len() int {
+	return pm.hub.Len()
+}
+
+//* 迭代器 [return true -> 继续运行] [return false -> 停止运行] */
+func (pm *PersistMapS) Iterator(cb func(n int, k string, v interface{}) bool) {
+	pm.hub.Iterator(cb)
+}
+
+//* 压缩日志[以当前内容重写] */
+func (pm *PersistMapS) Compact() error {
+	defer pm.lock.Unlock()
+	pm.lock.Lock()
+	return pm.wal.rewrite(func(write func(op byte, payload []byte) error) error {
+		if err := write(walOpReset, nil); err != nil {
+			return err
+		}
+		var err error
+		pm.hub.Iterator(func(n int, k string, v interface{}) bool {
+			b, encodeErr := pm.codec.Encode(v)
+			if encodeErr != nil {
+				return true
+			}
+			err = write(walOpSet, encodeKV(k, b))
+			return err == nil
+		})
+		return err
+	})
+}
+
+//* 获取并清除最近一次写入错误 */
+func (pm *PersistMapS) Err() error {
+	defer pm.lock.Unlock()
+	pm.lock.Lock()
+	err := pm.err
+	pm.err = nil
+	return err
+}
+
+//* 刷盘 */
+func (pm *PersistMapS) Sync() error {
+	return pm.wal.flushNow()
+}
+
+//* 关闭日志 */
+func (pm *PersistMapS) Close() error {
+	defer pm.lock.Unlock()
+	pm.lock.Lock()
+	return pm.wal.close()
+}
@@ -0,0 +1,261 @@
+package model
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+//* 字符串编解码 */
+var persistStringCodec = PersistCodecS{
+	Encode: func(v interface{}) ([]byte, error) {
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.New("not string")
+		}
+		return []byte(s), nil
+	},
+	Decode: func(b []byte) (interface{}, error) {
+		return string(b), nil
+	},
+}
+
+//* 统计日志分段数 */
+func walSegments(t *testing.T, dir string) int {
+	names, err := filepath.Glob(filepath.Join(dir, "*.wal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(names)
+}
+
+func TestPersistQueueReplay(t *testing.T) {
+	cases := []struct {
+		name string
+		ops  func(pq *PersistQueueS)
+		want []interface{}
+	}{
+		{"push", func(pq *PersistQueueS) {
+			pq.Push("a")
+			pq.Push("b")
+		}, []interface{}{"a", "b"}},
+		{"unshift", func(pq *PersistQueueS) {
+			pq.Push("b")
+			pq.UnShift("a")
+		}, []interface{}{"a", "b"}},
+		{"shift", func(pq *PersistQueueS) {
+			pq.Push("a")
+			pq.Push("b")
+			pq.Push("c")
+			pq.Shift()
+		}, []interface{}{"b", "c"}},
+		{"pop", func(pq *PersistQueueS) {
+			pq.Push("a")
+			pq.Push("b")
+			pq.Pop()
+		}, []interface{}{"a"}},
+		{"empty", func(pq *PersistQueueS) {
+			pq.Push("a")
+			pq.Shift()
+		}, []interface{}{}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "wal")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			pq, err := new(PersistQueueS).New(dir, 1<<20, -1, persistStringCodec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.ops(pq)
+			if err := pq.Close(); err != nil {
+				t.Fatal(err)
+			}
+			replayed, err := new(PersistQueueS).New(dir, 1<<20, -1, persistStringCodec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer replayed.Close()
+			if got := replayed.ToArrayV(); len(got) != len(c.want) || (len(got) > 0 && !reflect.DeepEqual(got, c.want)) {
+				t.Fatalf("replay = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestPersistQueueCompact(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// 小分段触发滚动
+	pq, err := new(PersistQueueS).New(dir, 32, 0, persistStringCodec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{"a", "b", "c", "d", "e", "f"} {
+		pq.Push(v)
+	}
+	pq.Shift()
+	pq.Shift()
+	if n := walSegments(t, dir); n < 2 {
+		t.Fatalf("segments before compact = %d, want >= 2", n)
+	}
+	if err := pq.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if n := walSegments(t, dir); n != 1 {
+		t.Fatalf("segments after compact = %d, want 1", n)
+	}
+	pq.Push("g")
+	pq.Close()
+	replayed, err := new(PersistQueueS).New(dir, 32, 0, persistStringCodec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replayed.Close()
+	want := []interface{}{"c", "d", "e", "f", "g"}
+	if got := replayed.ToArrayV(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("replay after compact = %v, want %v", got, want)
+	}
+}
+
+func TestPersistQueueTornTail(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	pq, err := new(PersistQueueS).New(dir, 1<<20, 0, persistStringCodec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pq.Push("a")
+	pq.Push("b")
+	pq.Close()
+	// 截去最后一条记录的尾部字节
+	path := filepath.Join(dir, "00000001.wal")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(path, info.Size()-1); err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := new(PersistQueueS).New(dir, 1<<20, 0, persistStringCodec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed.Push("c")
+	replayed.Close()
+	replayed, err = new(PersistQueueS).New(dir, 1<<20, 0, persistStringCodec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replayed.Close()
+	want := []interface{}{"a", "c"}
+	if got := replayed.ToArrayV(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("replay after torn tail = %v, want %v", got, want)
+	}
+}
+
+func TestPersistMapReplay(t *testing.T) {
+	cases := []struct {
+		name    string
+		ops     func(pm *PersistMapS)
+		compact bool
+		want    map[string]interface{}
+	}{
+		{"set", func(pm *PersistMapS) {
+			pm.Set("a", "1")
+			pm.Set("b", "2")
+		}, false, map[string]interface{}{"a": "1", "b": "2"}},
+		{"overwrite", func(pm *PersistMapS) {
+			pm.Set("a", "1")
+			pm.Set("a", "2")
+		}, false, map[string]interface{}{"a": "2"}},
+		{"del", func(pm *PersistMapS) {
+			pm.Set("a", "1")
+			pm.Set("b", "2")
+			pm.Del("a")
+		}, false, map[string]interface{}{"b": "2"}},
+		{"undecodable", func(pm *PersistMapS) {
+			pm.Set("a", "1")
+			pm.Set("a", 2)
+		}, false, map[string]interface{}{}},
+		{"compact", func(pm *PersistMapS) {
+			pm.Set("a", "1")
+			pm.Set("b", "2")
+			pm.Pop("b")
+			pm.Set("c", "3")
+		}, true, map[string]interface{}{"a": "1", "c": "3"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "wal")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			pm, err := new(PersistMapS).New(dir, 1<<20, -1, persistStringCodec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.ops(pm)
+			if c.compact {
+				if err := pm.Compact(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			pm.Close()
+			replayed, err := new(PersistMapS).New(dir, 1<<20, -1, persistStringCodec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer replayed.Close()
+			got := map[string]interface{}{}
+			replayed.Iterator(func(n int, k string, v interface{}) bool {
+				got[k] = v
+				return true
+			})
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("replay = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestPersistSyncClose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// 合并刷盘模式下Sync立即落盘
+	pq, err := new(PersistQueueS).New(dir, 1<<20, 60000, persistStringCodec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pq.Push("a")
+	if err := pq.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if err := pq.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// 关闭后写入仅记录错误
+	pq.Push("b")
+	if pq.Err() == nil {
+		t.Fatal("push after close should record an error")
+	}
+	if err := pq.Close(); err != nil {
+		t.Fatalf("second close = %v", err)
+	}
+}
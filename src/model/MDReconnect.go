@@ -0,0 +1,146 @@
+/**
+===========================================================================
+ * 客户端重连策略
+ * Client reconnect policy
+ * delay = min(MaxDelay, BaseDelay * 2^attempt) * (1 - Jitter * rand)
+ * 连接失败时依次切换至下一地址,收到重定向则立即连接指定地址
+ * 连接成功后断开则首次重连仍使用当前地址
+===========================================================================
+*/
+package model
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+//* 连接状态[Reconnect -> State事件] */
+const (
+	ConnConnecting = "connecting"
+	ConnConnected  = "connected"
+	ConnBackoff    = "backoff"
+	ConnStopped    = "stopped"
+	ConnFailed     = "failed"
+)
+
+//* 连接状态事件 */
+type ConnEventS struct {
+	State   string
+	Host    string
+	Attempt int
+	// 下次重连等待毫秒数
+	Delay int
+}
+
+//* 重连策略 */
+type ReconnectS struct {
+	Hosts     []string
+	BaseDelay int
+	MaxDelay  int
+	// 抖动比例[0 - 1]
+	Jitter float64
+	// 最大连续失败次数[<=0则不限]
+	MaxAttempts int
+
+	index     int
+	attempt   int
+	redirect  bool
+	connected bool
+	// 各策略独立随机源[避免多个客户端同步退避]
+	random *rand.Rand
+	mutex  *sync.Mutex
+}
+
+//* 新建重连策略 */
+func (policy *ReconnectS) New(hosts []string, baseDelay, maxDelay int, jitter float64, maxAttempts int) *ReconnectS {
+	if jitter < 0 {
+		jitter = 0
+	}
+	if jitter > 1 {
+		jitter = 1
+	}
+	return &ReconnectS{
+		Hosts:       append([]string{}, hosts...),
+		BaseDelay:   baseDelay,
+		MaxDelay:    maxDelay,
+		Jitter:      jitter,
+		MaxAttempts: maxAttempts,
+		random:      rand.New(rand.NewSource(time.Now().UnixNano())),
+		mutex:       new(sync.Mutex),
+	}
+}
+
+//* 当前连接地址 */
+func (policy *ReconnectS) Host() string {
+	policy.mutex.Lock()
+	defer policy.mutex.Unlock()
+	if len(policy.Hosts) == 0 {
+		return ""
+	}
+	return policy.Hosts[policy.index%len(policy.Hosts)]
+}
+
+//* 当前连续失败次数 */
+func (policy *ReconnectS) Attempt() int {
+	policy.mutex.Lock()
+	defer policy.mutex.Unlock()
+	return policy.attempt
+}
+
+//* 连接成功后重置 */
+func (policy *ReconnectS) Reset() {
+	policy.mutex.Lock()
+	defer policy.mutex.Unlock()
+	policy.attempt = 0
+	policy.connected = true
+}
+
+//* 重定向至指定地址[下次连接立即使用] */
+func (policy *ReconnectS) Redirect(host string) {
+	policy.mutex.Lock()
+	defer policy.mutex.Unlock()
+	index := -1
+	for k, v := range policy.Hosts {
+		if v == host {
+			index = k
+			break
+		}
+	}
+	if index == -1 {
+		policy.Hosts = append(policy.Hosts, host)
+		index = len(policy.Hosts) - 1
+	}
+	policy.index = index
+	policy.redirect = true
+	policy.connected = false
+}
+
+//* 记录一次失败,切换地址并返回等待时间[超出最大次数则返回false] */
+func (policy *ReconnectS) Next() (time.Duration, bool) {
+	policy.mutex.Lock()
+	defer policy.mutex.Unlock()
+	if policy.redirect {
+		policy.redirect = false
+		return 0, true
+	}
+	policy.attempt++
+	if policy.MaxAttempts > 0 && policy.attempt > policy.MaxAttempts {
+		return 0, false
+	}
+	// 连接成功后断开则重试当前地址
+	if policy.connected {
+		policy.connected = false
+	} else if len(policy.Hosts) > 0 {
+		policy.index = (policy.index + 1) % len(policy.Hosts)
+	}
+	delay := float64(policy.MaxDelay)
+	// 避免位移溢出
+	if policy.attempt < 31 {
+		if backoff := float64(policy.BaseDelay) * float64(int64(1)<<uint(policy.attempt-1)); backoff < delay {
+			delay = backoff
+		}
+	}
+	delay -= delay * policy.Jitter * policy.random.Float64()
+	return time.Duration(delay) * time.Millisecond, true
+}
@@ -0,0 +1,89 @@
+/**
+===========================================================================
+ * Commander路由表达式
+ * Commander routing expression
+ * <term>,<term>|<strategy>
+ *   term -> NeuronId | /Service[能力] | key=value[标签] | *[全部]
+ *   strategy -> all[默认] | rr[轮询] | least[最低负载] | random[随机]
+ * 例: region=cn,role=crawler|least  /ExampleSubscribe|rr
+===========================================================================
+*/
+package model
+
+import (
+	"strings"
+)
+
+//* 路由表达式 */
+type RouteS struct {
+	Raw      string
+	NeuronId string
+	Labels   map[string]string
+	Services []string
+	Strategy string
+}
+
+//* 解析路由表达式 */
+func (route *RouteS) Parse(expr string) *RouteS {
+	result := &RouteS{
+		Raw:      strings.TrimSpace(expr),
+		Labels:   make(map[string]string),
+		Strategy: "all",
+	}
+	terms := result.Raw
+	if index := strings.LastIndex(terms, "|"); index != -1 {
+		if strategy := strings.TrimSpace(terms[index+1:]); strategy != "" {
+			result.Strategy = strategy
+		}
+		terms = terms[:index]
+	}
+	for _, v := range strings.Split(terms, ",") {
+		v = strings.TrimSpace(v)
+		switch {
+		case v == "" || v == "*":
+		case strings.HasPrefix(v, "/"):
+			result.Services = append(result.Services, v)
+		case strings.Contains(v, "="):
+			kv := strings.SplitN(v, "=", 2)
+			result.Labels[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		default:
+			result.NeuronId = v
+		}
+	}
+	return result
+}
+
+//* 是否为单一节点编号[兼容原NeuronId写法] */
+func (route *RouteS) IsExact() bool {
+	return route.NeuronId != "" && len(route.Labels) == 0 && len(route.Services) == 0 && route.Strategy == "all"
+}
+
+//* 是否为全部节点[原空字符串广播] */
+func (route *RouteS) IsBroadcast() bool {
+	return route.NeuronId == "" && len(route.Labels) == 0 && len(route.Services) == 0 && route.Strategy == "all"
+}
+
+//* 判断节点是否匹配 */
+func (route *RouteS) Match(node NodeS) bool {
+	if route.NeuronId != "" && route.NeuronId != node.NeuronId {
+		return false
+	}
+	for k, v := range route.Labels {
+		if node.Labels[k] != v {
+			return false
+		}
+	}
+	for _, v := range route.Services {
+		found := false
+		for _, vv := range node.Services {
+			if v == vv {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,212 @@
+/**
+===========================================================================
+ * 指令调度
+ * Command scheduling
+ * PriorityQueueS -> 优先级队列[同优先级先进先出]
+ * DelayQueueS -> 延迟队列[按到期时间取出]
+ * RateS -> 令牌桶限速
+===========================================================================
+*/
+package model
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+//* 指令优先级[数值越大越优先,零值为Normal] */
+const (
+	PriorityLow    = -1
+	PriorityNormal = 0
+	PriorityHigh   = 1
+	PriorityUrgent = 2
+)
+
+type scheduleItemS struct {
+	value    interface{}
+	priority int
+	at       time.Time
+	seq      uint64
+}
+
+//* 堆实现[byTime -> 按到期时间,否则按优先级] */
+type scheduleHeapS struct {
+	items  []scheduleItemS
+	byTime bool
+}
+
+func (h *scheduleHeapS) Len() int {
+	return len(h.items)
+}
+
+func (h *scheduleHeapS) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	if h.byTime {
+		if !a.at.Equal(b.at) {
+			return a.at.Before(b.at)
+		}
+	} else if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	return a.seq < b.seq
+}
+
+func (h *scheduleHeapS) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+}
+
+func (h *scheduleHeapS) Push(x interface{}) {
+	h.items = append(h.items, x.(scheduleItemS))
+}
+
+func (h *scheduleHeapS) Pop() interface{} {
+	item := h.items[len(h.items)-1]
+	h.items[len(h.items)-1] = scheduleItemS{}
+	h.items = h.items[:len(h.items)-1]
+	return item
+}
+
+//* 优先级队列 */
+type PriorityQueueS struct {
+	heap *scheduleHeapS
+	seq  uint64
+	lock *sync.Mutex
+}
+
+//* 新建优先级队列 */
+func (queue *PriorityQueueS) New() *PriorityQueueS {
+	return &PriorityQueueS{
+		heap: &scheduleHeapS{},
+		lock: new(sync.Mutex),
+	}
+}
+
+//* 入队 */
+func (queue *PriorityQueueS) Push(value interface{}, priority int) {
+	if value == nil {
+		return
+	}
+	defer queue.lock.Unlock()
+	queue.lock.Lock()
+	queue.seq++
+	heap.Push(queue.heap, scheduleItemS{value: value, priority: priority, seq: queue.seq})
+}
+
+//* 取出优先级最高的元素 */
+func (queue *PriorityQueueS) Pop() interface{} {
+	defer queue.lock.Unlock()
+	queue.lock.Lock()
+	if queue.heap.Len() == 0 {
+		return nil
+	}
+	return heap.Pop(queue.heap).(scheduleItemS).value
+}
+
+//* 获取长度 */
+func (queue *PriorityQueueS) Len() int {
+	defer queue.lock.Unlock()
+	queue.lock.Lock()
+	return queue.heap.Len()
+}
+
+//* 判断是否为空 */
+func (queue *PriorityQueueS) IsEmpty() bool {
+	return queue.Len() == 0
+}
+
+//* 延迟队列 */
+type DelayQueueS struct {
+	heap *scheduleHeapS
+	seq  uint64
+	lock *sync.Mutex
+}
+
+//* 新建延迟队列 */
+func (queue *DelayQueueS) New() *DelayQueueS {
+	return &DelayQueueS{
+		heap: &scheduleHeapS{byTime: true},
+		lock: new(sync.Mutex),
+	}
+}
+
+//* 入队[at -> 到期时间] */
+func (queue *DelayQueueS) Push(value interface{}, at time.Time) {
+	if value == nil {
+		return
+	}
+	defer queue.lock.Unlock()
+	queue.lock.Lock()
+	queue.seq++
+	heap.Push(queue.heap, scheduleItemS{value: value, at: at, seq: queue.seq})
+}
+
+//* 取出全部已到期元素 */
+func (queue *DelayQueueS) PopDue(now time.Time) []interface{} {
+	defer queue.lock.Unlock()
+	queue.lock.Lock()
+	values := make([]interface{}, 0)
+	for queue.heap.Len() > 0 && !queue.heap.items[0].at.After(now) {
+		values = append(values, heap.Pop(queue.heap).(scheduleItemS).value)
+	}
+	return values
+}
+
+//* 清空队列 */
+func (queue *DelayQueueS) Reset() {
+	defer queue.lock.Unlock()
+	queue.lock.Lock()
+	queue.heap = &scheduleHeapS{byTime: true}
+}
+
+//* 获取长度 */
+func (queue *DelayQueueS) Len() int {
+	defer queue.lock.Unlock()
+	queue.lock.Lock()
+	return queue.heap.Len()
+}
+
+//* 令牌桶 */
+type RateS struct {
+	// 每秒令牌数[<=0则不限速]
+	Rate  float64
+	Burst float64
+
+	tokens float64
+	last   time.Time
+	lock   *sync.Mutex
+}
+
+//* 新建令牌桶 */
+func (rate *RateS) New(perSecond int, burst int) *RateS {
+	if burst <= 0 {
+		burst = 1
+	}
+	return &RateS{
+		Rate:   float64(perSecond),
+		Burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+		lock:   new(sync.Mutex),
+	}
+}
+
+//* 预占一个令牌并返回需等待的时间 */
+func (rate *RateS) Reserve() time.Duration {
+	if rate.Rate <= 0 {
+		return 0
+	}
+	defer rate.lock.Unlock()
+	rate.lock.Lock()
+	now := time.Now()
+	rate.tokens += now.Sub(rate.last).Seconds() * rate.Rate
+	if rate.tokens > rate.Burst {
+		rate.tokens = rate.Burst
+	}
+	rate.last = now
+	rate.tokens--
+	if rate.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-rate.tokens / rate.Rate * float64(time.Second))
+}
@@ -0,0 +1,435 @@
+/**
+===========================================================================
+ * 集群共享存储
+ * Cluster shared store
+ * MemoryStoreS -> 进程内实现[单机/测试]
+ * StoreQueueS -> 基于StoreI列表的QueueI[多Commander共享队列]
+ * StoreMapS -> 基于StoreI散列的MapI[多Commander共享容器]
+===========================================================================
+*/
+package model
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+//* 进程内共享存储 */
+type MemoryStoreS struct {
+	leases map[string]memoryLeaseS
+	lists  map[string][][]byte
+	hashes map[string]map[string][]byte
+	lock   *sync.Mutex
+}
+
+type memoryLeaseS struct {
+	value  string
+	expire time.Time
+}
+
+//* 新建进程内共享存储 */
+func (store *MemoryStoreS) New() *MemoryStoreS {
+	return &MemoryStoreS{
+		leases: make(map[string]memoryLeaseS),
+		lists:  make(map[string][][]byte),
+		hashes: make(map[string]map[string][]byte),
+		lock:   new(sync.Mutex),
+	}
+}
+
+//* 读取未过期租约 */
+func (store *MemoryStoreS) lease(key string) (memoryLeaseS, bool) {
+	lease, found := store.leases[key]
+	if found && time.Now().After(lease.expire) {
+		delete(store.leases, key)
+		return lease, false
+	}
+	return lease, found
+}
+
+//* 抢占租约 */
+func (store *MemoryStoreS) Acquire(key, value string, ttl int) (bool, error) {
+	defer store.lock.Unlock()
+	store.lock.Lock()
+	if _, found := store.lease(key); found {
+		return false, nil
+	}
+	store.leases[key] = memoryLeaseS{value, time.Now().Add(time.Duration(ttl) * time.Millisecond)}
+	return true, nil
+}
+
+//* 续约 */
+func (store *MemoryStoreS) Renew(key, value string, ttl int) (bool, error) {
+	defer store.lock.Unlock()
+	store.lock.Lock()
+	if lease, found := store.lease(key); !found || lease.value != value {
+		return false, nil
+	}
+	store.leases[key] = memoryLeaseS{value, time.Now().Add(time.Duration(ttl) * time.Millisecond)}
+	return true, nil
+}
+
+//* 释放租约 */
+func (store *MemoryStoreS) Release(key, value string) error {
+	defer store.lock.Unlock()
+	store.lock.Lock()
+	if lease, found := store.lease(key); found && lease.value == value {
+		delete(store.leases, key)
+	}
+	return nil
+}
+
+//* 读取租约值 */
+func (store *MemoryStoreS) Get(key string) (string, error) {
+	defer store.lock.Unlock()
+	store.lock.Lock()
+	lease, _ := store.lease(key)
+	return lease.value, nil
+}
+
+//* 列表写入[head -> 队首] */
+func (store *MemoryStoreS) ListPush(key string, value []byte, head bool) error {
+	defer store.lock.Unlock()
+	store.lock.Lock()
+	if head {
+		store.lists[key] = append([][]byte{value}, store.lists[key]...)
+	} else {
+		store.lists[key] = append(store.lists[key], value)
+	}
+	return nil
+}
+
+//* 列表取出[head -> 队首] */
+func (store *MemoryStoreS) ListPop(key string, head bool) ([]byte, error) {
+	defer store.lock.Unlock()
+	store.lock.Lock()
+	list := store.lists[key]
+	if len(list) == 0 {
+		return nil, nil
+	}
+	var value []byte
+	if head {
+		value, list = list[0], list[1:]
+	} else {
+		value, list = list[len(list)-1], list[:len(list)-1]
+	}
+	if len(list) == 0 {
+		delete(store.lists, key)
+	} else {
+		store.lists[key] = list
+	}
+	return value, nil
+}
+
+//* 列表读取 */
+func (store *MemoryStoreS) ListIndex(key string, index int) ([]byte, error) {
+	defer store.lock.Unlock()
+	store.lock.Lock()
+	list := store.lists[key]
+	if index < 0 || index >= len(list) {
+		return nil, nil
+	}
+	return list[index], nil
+}
+
+//* 列表长度 */
+func (store *MemoryStoreS) ListLen(key string) (int, error) {
+	defer store.lock.Unlock()
+	store.lock.Lock()
+	return len(store.lists[key]), nil
+}
+
+//* 列表全部元素 */
+func (store *MemoryStoreS) ListRange(key string) ([][]byte, error) {
+	defer store.lock.Unlock()
+	store.lock.Lock()
+	return append([][]byte{}, store.lists[key]...), nil
+}
+
+//* 按前缀列出列表键 */
+func (store *MemoryStoreS) Keys(prefix string) ([]string, error) {
+	defer store.lock.Unlock()
+	store.lock.Lock()
+	keys := make([]string, 0)
+	for k := range store.lists {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+//* 散列写入 */
+func (store *MemoryStoreS) HashSet(key, field string, value []byte) error {
+	defer store.lock.Unlock()
+	store.lock.Lock()
+	hash, found := store.hashes[key]
+	if !found {
+		hash = make(map[string][]byte)
+		store.hashes[key] = hash
+	}
+	hash[field] = value
+	return nil
+}
+
+//* 散列读取 */
+func (store *MemoryStoreS) HashGet(key, field string) ([]byte, error) {
+	defer store.lock.Unlock()
+	store.lock.Lock()
+	return store.hashes[key][field], nil
+}
+
+//* 散列删除 */
+func (store *MemoryStoreS) HashDel(key, field string) error {
+	defer store.lock.Unlock()
+	store.lock.Lock()
+	if hash, found := store.hashes[key]; found {
+		delete(hash, field)
+		if len(hash) == 0 {
+			delete(store.hashes, key)
+		}
+	}
+	return nil
+}
+
+//* 散列长度 */
+func (store *MemoryStoreS) HashLen(key string) (int, error) {
+	defer store.lock.Unlock()
+	store.lock.Lock()
+	return len(store.hashes[key]), nil
+}
+
+//* 散列全部元素 */
+func (store *MemoryStoreS) HashAll(key string) (map[string][]byte, error) {
+	defer store.lock.Unlock()
+	store.lock.Lock()
+	hash := make(map[string][]byte, len(store.hashes[key]))
+	for k, v := range store.hashes[key] {
+		hash[k] = v
+	}
+	return hash, nil
+}
+
+//* 共享队列[实现QueueI] */
+type StoreQueueS struct {
+	Key   string
+	store StoreI
+	codec PersistCodecS
+	err   error
+	lock  *sync.Mutex
+}
+
+//* 新建共享队列 */
+func (queue *StoreQueueS) New(store StoreI, key string, codec PersistCodecS) *StoreQueueS {
+	return &StoreQueueS{
+		Key:   key,
+		store: store,
+		codec: codec,
+		lock:  new(sync.Mutex),
+	}
+}
+
+//* 记录错误 */
+func (queue *StoreQueueS) setErr(err error) {
+	if err == nil {
+		return
+	}
+	defer queue.lock.Unlock()
+	queue.lock.Lock()
+	queue.err = err
+}
+
+//* 获取最近一次错误 */
+func (queue *StoreQueueS) Err() error {
+	defer queue.lock.Unlock()
+	queue.lock.Lock()
+	return queue.err
+}
+
+func (queue *StoreQueueS) push(value interface{}, head bool) {
+	if value == nil {
+		return
+	}
+	b, err := queue.codec.Encode(value)
+	if err != nil {
+		queue.setErr(err)
+		return
+	}
+	queue.setErr(queue.store.ListPush(queue.Key, b, head))
+}
+
+func (queue *StoreQueueS) decode(b []byte, err error) interface{} {
+	if err != nil {
+		queue.setErr(err)
+		return nil
+	}
+	if b == nil {
+		return nil
+	}
+	value, err := queue.codec.Decode(b)
+	if err != nil {
+		queue.setErr(err)
+		return nil
+	}
+	return value
+}
+
+//* 入队尾 */
+func (queue *StoreQueueS) Push(value interface{}) {
+	queue.push(value, false)
+}
+
+//* 入队首 */
+func (queue *StoreQueueS) UnShift(value interface{}) {
+	queue.push(value, true)
+}
+
+//* 出队首 */
+func (queue *StoreQueueS) Shift() interface{} {
+	return queue.decode(queue.store.ListPop(queue.Key, true))
+}
+
+//* 取队尾 */
+func (queue *StoreQueueS) Pop() interface{} {
+	return queue.decode(queue.store.ListPop(queue.Key, false))
+}
+
+//* 出队首(不出队) */
+func (queue *StoreQueueS) ShiftPeek() interface{} {
+	return queue.decode(queue.store.ListIndex(queue.Key, 0))
+}
+
+//* 获取长度 */
+func (queue *StoreQueueS) Len() int {
+	n, err := queue.store.ListLen(queue.Key)
+	queue.setErr(err)
+	return n
+}
+
+//* 判断是否为空 */
+func (queue *StoreQueueS) IsEmpty() bool {
+	return queue.Len() == 0
+}
+
+//* 转数组对象 */
+func (queue *StoreQueueS) ToArrayV() []interface{} {
+	list, err := queue.store.ListRange(queue.Key)
+	if err != nil {
+		queue.setErr(err)
+		return nil
+	}
+	values := make([]interface{}, 0, len(list))
+	for _, v := range list {
+		if value := queue.decode(v, nil); value != nil {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+//* 共享容器[实现MapI] */
+type StoreMapS struct {
+	Key   string
+	store StoreI
+	codec PersistCodecS
+	err   error
+	lock  *sync.Mutex
+}
+
+//* 新建共享容器 */
+func (hub *StoreMapS) New(store StoreI, key string, codec PersistCodecS) *StoreMapS {
+	return &StoreMapS{
+		Key:   key,
+		store: store,
+		codec: codec,
+		lock:  new(sync.Mutex),
+	}
+}
+
+//* 记录错误 */
+func (hub *StoreMapS) setErr(err error) {
+	if err == nil {
+		return
+	}
+	defer hub.lock.Unlock()
+	hub.lock.Lock()
+	hub.err = err
+}
+
+//* 获取最近一次错误 */
+func (hub *StoreMapS) Err() error {
+	defer hub.lock.Unlock()
+	hub.lock.Lock()
+	return hub.err
+}
+
+func (hub *StoreMapS) decode(b []byte, err error) interface{} {
+	if err != nil {
+		hub.setErr(err)
+		return nil
+	}
+	if b == nil {
+		return nil
+	}
+	value, err := hub.codec.Decode(b)
+	if err != nil {
+		hub.setErr(err)
+		return nil
+	}
+	return value
+}
+
+//* 获取元素 */
+func (hub *StoreMapS) Get(k string) interface{} {
+	return hub.decode(hub.store.HashGet(hub.Key, k))
+}
+
+//* 设置元素 */
+func (hub *StoreMapS) Set(k string, v interface{}) {
+	b, err := hub.codec.Encode(v)
+	if err != nil {
+		hub.setErr(err)
+		return
+	}
+	hub.setErr(hub.store.HashSet(hub.Key, k, b))
+}
+
+//* 取出元素 */
+func (hub *StoreMapS) Pop(k string) interface{} {
+	value := hub.Get(k)
+	hub.Del(k)
+	return value
+}
+
+//* 删除元素 */
+func (hub *StoreMapS) Del(k string) {
+	hub.setErr(hub.store.HashDel(hub.Key, k))
+}
+
+//* 获取长度 */
+func (hub *StoreMapS) Len() int {
+	n, err := hub.store.HashLen(hub.Key)
+	hub.setErr(err)
+	return n
+}
+
+//* 迭代器[遍历快照] */
+func (hub *StoreMapS) Iterator(cb func(n int, k string, v interface{}) bool) {
+	hash, err := hub.store.HashAll(hub.Key)
+	if err != nil {
+		hub.setErr(err)
+		return
+	}
+	n := 0
+	for k, b := range hash {
+		value := hub.decode(b, nil)
+		if value == nil {
+			continue
+		}
+		if !cb(n, k, value) {
+			return
+		}
+		n++
+	}
+}
@@ -98,6 +98,21 @@ func (hub *SyncMapHub) Set(k string, v interface{}) {
 	shard.Unlock()
 }
 
+//* 获取元素,不存在则设置[loaded -> 元素是否已存在] */
+func (hub *SyncMapHub) GetOrSet(k string, v interface{}) (interface{}, bool) {
+	if hub.mapShard == nil {
+		return nil, false
+	}
+	shard := hub.GetShard(k)
+	shard.Lock()
+	defer shard.Unlock()
+	if old, found := shard.item[k]; found {
+		return old, true
+	}
+	shard.item[k] = v
+	return v, false
+}
+
 //* 通过map设置元素 */
 func (hub *SyncMapHub) SetByMap(data map[string]interface{}) {
 	if hub.mapShard == nil {
@@ -189,17 +204,23 @@ func (hub *SyncMapHub) Convert2Map() map[string]interface{} {
 }
 
 //* 迭代器 [return true -> 继续运行] [return false -> 停止运行] */
+//* 先取快照再回调,回调期间不持有分区锁 */
 func (hub *SyncMapHub) Iterator(cb func(n int, k string, v interface{}) bool) {
 	if hub.mapShard == nil {
 		return
 	}
-	var n int
-	for item := range hub.DeepCopyMap() {
-		if cb(n, item.Key, item.Value) {
-			n++
-			continue
+	items := make([]syncMapTuple, 0, hub.Len())
+	for _, shard := range hub.mapShard {
+		shard.RLock()
+		for key, val := range shard.item {
+			items = append(items, syncMapTuple{key, val})
+		}
+		shard.RUnlock()
+	}
+	for n, item := range items {
+		if !cb(n, item.Key, item.Value) {
+			return
 		}
-		return
 	}
 }
 
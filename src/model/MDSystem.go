@@ -48,6 +48,8 @@ type GMessageS struct {
 	Head string
 	Tag  string
 	Cmds []interface{}
+	// 追踪编号[二进制格式为独立字段,CodecTextTrace以TraceSeparator附于ID之后]
+	Trace string
 }
 
 //* TCP通信中心为SyncMap形式 */
@@ -114,4 +116,172 @@ type UDPPacket struct {
 type CommanderPiece struct {
 	NeuronId string
 	GMessage GMessageS
-}
\ No newline at end of file
+	// 优先级[见PriorityUrgent等]
+	Priority int
+	// 不早于该时间发送[零值则立即发送]
+	NotBefore time.Time
+}
+//* CommanderPiece投递记录 */
+type DeliveryS struct {
+	NeuronId string
+	Piece    CommanderPiece
+	// 已发送次数
+	Attempts int
+	// 下次重发时间
+	NextRetry time.Time
+}
+
+//* Commander节点注册信息 */
+type NodeS struct {
+	NeuronId   string
+	RemoteAddr string
+	Version    string
+	// 节点状态[alive | suspect | dead]
+	State string
+	// 连接时间
+	ConnectTime time.Time
+	// 最后心跳时间
+	LastHeart time.Time
+	// 连续错过心跳次数
+	Missed   int
+	Services []string
+	Labels   map[string]string
+	// 是否通过握手认证[标签role仅在认证后生效]
+	Verified bool
+	// 最新心跳指标
+	Metric NodeMetricS
+}
+
+//* 节点心跳指标 */
+type NodeMetricS struct {
+	// 1分钟平均负载[不支持的平台为-1]
+	Load   float64
+	NumCPU int
+	// 内存[Byte]
+	MemAlloc  uint64
+	MemSys    uint64
+	Goroutine int
+	// 运行秒数
+	Uptime int64
+	// 队列深度[map[Root/Queue]Len]
+	QueueDepth map[string]int
+	// 任务额度[map[Root]Credit,缺省或<0不限制,0为繁忙]
+	Credit map[string]int
+}
+
+//* GMessage编码格式 */
+const (
+	// 文本格式 ID#HeadTag#cmd#cmd**[不携带追踪编号]
+	CodecText = "text"
+	// 文本格式 ID|Trace#HeadTag#cmd#cmd**[协商后才拆分ID中的追踪编号]
+	CodecTextTrace = "text2"
+	// 二进制格式 [magic 2][version 1][uvarint len][ID][Head][Tag][count][type cmd...][Trace]
+	CodecBinary = "bin1"
+)
+
+//* 二进制GMessage帧头 */
+var FrameMagic = []byte{'N', 'F'}
+
+//* 二进制GMessage版本 */
+const FrameVersion byte = 1
+
+//* 二进制GMessage参数类型 */
+const (
+	FrameBytes byte = iota
+	FrameString
+	FrameInt
+	FrameJSON
+)
+
+//* Websocket连接状态 */
+type ConnStateS struct {
+	// 编码格式
+	Codec string
+	// 对端使用的秘钥编号
+	KeyId string
+	// 握手挑战码
+	Challenge string
+	// 已验证的节点编号
+	NeuronId string
+}
+
+//* 反向隧道 */
+type TunnelS struct {
+	Id string
+	// Commander监听地址
+	Listen   string
+	NeuronId string
+	// 节点内网目标地址
+	Target     string
+	Streams    int
+	CreateTime time.Time
+}
+
+//* 加密信封帧头 */
+var EnvelopeMagic = []byte{'N', 'E'}
+
+//* 加密信封版本[AES-256-GCM] */
+const EnvelopeVersion byte = 1
+
+//* 文件传输结果 */
+type TransferS struct {
+	Id       string
+	NeuronId string
+	Name     string
+	Size     int64
+	// 传输结果[100 -> 完成 | 104 -> 超时 | 其他 -> 失败]
+	Code      int
+	Data      interface{}
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+//* 追踪编号分隔符[CodecTextTrace -> ID|Trace] */
+const TraceSeparator = "|"
+
+//* 追踪阶段 */
+const (
+	TraceQueued   = "queued"
+	TraceSent     = "sent"
+	TraceAcked    = "acked"
+	TraceBusy     = "busy"
+	TraceReceived = "received"
+	TraceExecuted = "executed"
+	TraceReplied  = "replied"
+	TraceDead     = "dead"
+)
+
+//* 追踪记录 */
+type TraceHopS struct {
+	Trace     string
+	MessageId string
+	// 记录阶段所在节点[Commander端为Commander]
+	NeuronId string
+	Stage    string
+	Time     time.Time
+	Detail   string `json:",omitempty"`
+}
+
+//* 参数校验错误 */
+type FieldErrorS struct {
+	Field   string
+	Rule    string
+	Message string
+}
+
+//* 认证方式 */
+const (
+	AuthAPIKey = "apikey"
+	AuthHMAC   = "hmac"
+	AuthJWT    = "jwt"
+)
+
+//* 认证身份 */
+type IdentityS struct {
+	// ClientId或JWT sub
+	Subject string
+	Method  string
+	Roles   []string
+	// JWT过期时间[其他方式为零值]
+	Expire time.Time `json:",omitempty"`
+}